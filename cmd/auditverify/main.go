@@ -0,0 +1,44 @@
+// Package main is the entry point of the audit log verification tool.
+// It checks the hash chain of an audit log file written by the gRPC server.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/audit"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: auditverify <audit-log-file>")
+		return 2
+	}
+
+	f, err := os.Open(filepath.Clean(args[0]))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open audit log: %v\n", err)
+		return 2
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to close audit log: %v\n", err)
+		}
+	}()
+
+	n, err := audit.Verify(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verification failed after %d valid records: %v\n", n, err)
+		return 1
+	}
+
+	fmt.Printf("audit log OK: %d records verified\n", n)
+
+	return 0
+}
@@ -0,0 +1,57 @@
+// Package main is the entry point of the configuration reference generator.
+// It writes the reference of every server configuration key and the JSON Schema of the configuration files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mrityunjoydey/go-grpc/src/common/config"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	flags := flag.NewFlagSet("configref", flag.ContinueOnError)
+	out := flags.String("out", "docs/config", "directory of the generated files")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	files, err := config.ReferenceFiles()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to render configuration reference: %v\n", err)
+		return 1
+	}
+
+	if err := os.MkdirAll(*out, 0750); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create %s: %v\n", *out, err)
+		return 1
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(*out, name)
+
+		if err := os.WriteFile(path, files[name], 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", path, err)
+			return 1
+		}
+
+		fmt.Println("wrote", path)
+	}
+
+	return 0
+}
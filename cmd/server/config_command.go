@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	config_pkg "github.com/mrityunjoydey/go-grpc/pkg/config"
+	"github.com/mrityunjoydey/go-grpc/src/common/config"
+)
+
+const configUsage = "usage: server config print|validate [--config <file>] [--profile <name>] [--<key>=<value>...]"
+
+// runConfig runs the config command: it loads the configuration like the server does, then prints it with
+// secrets redacted, or only checks it. Every invalid value is reported. It returns the exit code.
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || (args[0] != "print" && args[0] != "validate") {
+		fmt.Fprintln(stderr, configUsage)
+		return 2
+	}
+
+	cfg, err := config_pkg.LoadConfig(&config.Config{}, config_pkg.WithArgs(args[1:]))
+	if err != nil {
+		printConfigError(stderr, err)
+		return 1
+	}
+
+	if args[0] == "validate" {
+		fmt.Fprintln(stdout, "configuration is valid")
+		return 0
+	}
+
+	if err := config_pkg.Print(stdout, cfg); err != nil {
+		fmt.Fprintf(stderr, "failed to print configuration: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// printConfigError writes a configuration error, with one line per invalid value.
+func printConfigError(w io.Writer, err error) {
+	var ve *config_pkg.ValidationError
+	if !errors.As(err, &ve) {
+		fmt.Fprintf(w, "failed to load configuration: %v\n", err)
+		return
+	}
+
+	fmt.Fprintln(w, "invalid configuration:")
+
+	for _, f := range ve.Fields {
+		fmt.Fprintf(w, "  %v\n", f)
+	}
+}
@@ -3,36 +3,56 @@ package main
 
 import (
 	"context"
+	"maps"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/mrityunjoydey/go-grpc/pkg/audit"
 	config_pkg "github.com/mrityunjoydey/go-grpc/pkg/config"
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+	"github.com/mrityunjoydey/go-grpc/pkg/i18n"
+	"github.com/mrityunjoydey/go-grpc/pkg/idempotency"
 	"github.com/mrityunjoydey/go-grpc/pkg/logger"
 	"github.com/mrityunjoydey/go-grpc/src/common/config"
+	"github.com/mrityunjoydey/go-grpc/src/middleware"
 	"github.com/mrityunjoydey/go-grpc/src/server"
+	"github.com/mrityunjoydey/go-grpc/src/service/greeter"
+	"github.com/mrityunjoydey/go-grpc/src/store"
 )
 
 func main() {
-	cfg := &config.Config{}
-	// Load configuration
-	cfg, err := config_pkg.LoadConfig(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	// Load configuration from defaults, the configuration files, environment variables and flags. The watcher
+	// reloads it when the files change or the process receives SIGHUP.
+	configWatcher, err := config_pkg.NewWatcher(func() *config.Config { return &config.Config{} },
+		config_pkg.WithArgs(os.Args[1:]))
 	if err != nil {
 		panic("failed to load config: " + err.Error())
 	}
 
+	cfg := configWatcher.Current()
+
 	// Initialize logger
 	log, err := logger.NewZapLogger("grpc-server", cfg.App.LogToFile)
 	if err != nil {
 		panic("failed to create logger: " + err.Error())
 	}
 
+	if err := logger.SetLevel(cfg.Log.Level); err != nil {
+		panic("failed to set log level: " + err.Error())
+	}
+
 	// Create a context with a request ID for lifecycle logs
 	reqID := uuid.New().String()
-	ctx := context.WithValue(context.Background(), logger.FieldNameRequestId, reqID)
+	ctx := middleware.ContextWithRequestID(context.Background(), reqID)
 	lifecycleLogger := log.WithContext(ctx)
 
 	defer func() {
@@ -41,13 +61,111 @@ func main() {
 		}
 	}()
 
+	requestIDGenerator, err := middleware.RequestIDGeneratorByName(cfg.RequestID.Generator)
+	if err != nil {
+		lifecycleLogger.Fatal("invalid request ID configuration", zap.Error(err))
+	}
+
+	// Open the audit log sink
+	auditLogger, err := newAuditLogger(cfg.Audit)
+	if err != nil {
+		lifecycleLogger.Fatal("failed to open audit log", zap.Error(err))
+	}
+
+	if auditLogger != nil {
+		defer func() {
+			if err := auditLogger.Close(); err != nil {
+				lifecycleLogger.Error("failed to close audit log", zap.Error(err))
+			}
+		}()
+	}
+
+	// Open the greeting history store
+	greetingStore, err := newStore(cfg.Store)
+	if err != nil {
+		lifecycleLogger.Fatal("failed to open greeting store", zap.Error(err))
+	}
+
+	if greetingStore != nil {
+		defer func() {
+			if err := greetingStore.Close(); err != nil {
+				lifecycleLogger.Error("failed to close greeting store", zap.Error(err))
+			}
+		}()
+	}
+
+	// Open the idempotency store
+	idempotencyStore, err := newIdempotencyStore(cfg.Idempotency)
+	if err != nil {
+		lifecycleLogger.Fatal("failed to open idempotency store", zap.Error(err))
+	}
+
+	if idempotencyStore != nil {
+		defer func() {
+			if err := idempotencyStore.Close(); err != nil {
+				lifecycleLogger.Error("failed to close idempotency store", zap.Error(err))
+			}
+		}()
+	}
+
+	// Load the greeting templates, failing fast on invalid ones
+	greetings, err := greeter.NewGreetings(cfg.Greeter.Templates.Dir, cfg.Greeter.Templates.DefaultLocale)
+	if err != nil {
+		lifecycleLogger.Fatal("invalid greeting templates", zap.Error(err))
+	}
+
+	// Feature flags are evaluated per call and updated when the configuration is reloaded
+	featureFlags := flags.NewSet(featureFlagList(cfg.Flags), flags.WithLogger(log))
+
 	// Create and start server
-	srv := server.New(cfg.Server.Port, log)
+	srv := server.New(cfg.Server.Port, log,
+		server.WithRequestID(
+			middleware.WithRequestIDGenerator(requestIDGenerator),
+			middleware.WithRequestIDMaxLength(cfg.RequestID.MaxLength),
+			middleware.WithRequestIDRejectInvalid(cfg.RequestID.RejectInvalid),
+		),
+		server.WithDeadlines(deadlineOptions(cfg.Deadline)...),
+		server.WithAuditLogger(auditLogger),
+		server.WithIdempotency(idempotencyStore,
+			middleware.WithIdempotencyTTL(cfg.Idempotency.TTL),
+			middleware.WithIdempotencyKeyMaxLength(cfg.Idempotency.KeyMaxLength),
+		),
+		server.WithCache(cacheOptions(cfg.Cache)...),
+		server.WithFlags(featureFlags),
+		server.WithAdmin(cfg.Admin.Token.Value()),
+		server.WithGreeterOptions(
+			greeter.WithStreamLimits(streamLimits(cfg.Greeter.Stream)),
+			greeter.WithStreamGreetingsLimits(streamGreetingsLimits(cfg.Greeter.StreamGreetings)),
+			greeter.WithChatBufferSize(cfg.Greeter.Chat.BufferSize),
+			greeter.WithGreetings(greetings),
+			greeter.WithStore(greetingStore),
+			greeter.WithMaxOrderedGreetings(cfg.Greeter.MaxOrdered),
+			greeter.WithPageTokenKey([]byte(cfg.Greeter.PageTokenKey.Value())),
+		),
+		server.WithPayloadLogging(
+			middleware.WithPayloadMethods(cfg.Debug.PayloadMethods...),
+			middleware.WithPayloadMaxBytes(cfg.Debug.PayloadMaxBytes.Int()),
+			middleware.WithPayloadRedactedFields(cfg.Debug.RedactFields...),
+			middleware.WithDebugAuthorizer(middleware.DebugTokenAuthorizer(cfg.Debug.Token.Value())),
+		),
+	)
 
 	// Graceful shutdown
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	templates := &templateWatcher{ctx: ctx, catalog: greetings, logger: lifecycleLogger}
+	templates.update(cfg.Greeter.Templates)
+
+	// Apply the reloadable settings when the configuration changes
+	subscribeConfig(configWatcher, srv.Greeter(), templates, featureFlags, lifecycleLogger)
+
+	go func() {
+		if err := configWatcher.Watch(ctx, lifecycleLogger); err != nil {
+			lifecycleLogger.Error("failed to watch configuration", zap.Error(err))
+		}
+	}()
+
 	go func() {
 		if err := srv.Start(); err != nil {
 			lifecycleLogger.Fatal("gRPC server failed to start", zap.Error(err))
@@ -61,3 +179,197 @@ func main() {
 	lifecycleLogger.Info("Shutting down gRPC server")
 	srv.Stop()
 }
+
+// subscribeConfig applies the log level, the stream limits, the greeting templates and the feature flags of
+// reloaded configurations. Other settings take effect on restart. The server has no request rate limits, so the
+// stream limits, which bound what a client may send, are the limits reloaded live instead.
+func subscribeConfig(
+	w *config_pkg.Watcher[*config.Config],
+	svc *greeter.Service,
+	templates *templateWatcher,
+	featureFlags *flags.Set,
+	l logger.Logger,
+) {
+	w.Subscribe(func(c config_pkg.Change[*config.Config]) {
+		featureFlags.Update(featureFlagList(c.New.Flags))
+	}, "flags")
+
+	w.Subscribe(func(c config_pkg.Change[*config.Config]) {
+		if err := logger.SetLevel(c.New.Log.Level); err != nil {
+			l.Error("failed to set log level", zap.Error(err))
+		}
+	}, "log.level")
+
+	w.Subscribe(func(c config_pkg.Change[*config.Config]) {
+		svc.SetStreamLimits(streamLimits(c.New.Greeter.Stream))
+		svc.SetStreamGreetingsLimits(streamGreetingsLimits(c.New.Greeter.StreamGreetings))
+	}, "greeter.stream", "greeter.streamgreetings")
+
+	w.Subscribe(func(c config_pkg.Change[*config.Config]) {
+		cfg := c.New.Greeter.Templates
+
+		if c.Changed("greeter.templates.dir", "greeter.templates.defaultlocale") {
+			if err := greeter.ReloadGreetings(templates.catalog, cfg.Dir, cfg.DefaultLocale); err != nil {
+				l.Error("invalid greeting templates, keeping the current ones", zap.Error(err))
+				return
+			}
+		}
+
+		templates.update(cfg)
+	}, "greeter.templates")
+}
+
+// templateWatcher watches the directory of the greeting templates, and restarts watching when the directory
+// changes. update is not safe for concurrent use; configuration subscribers are called one at a time.
+type templateWatcher struct {
+	ctx     context.Context
+	catalog *i18n.Catalog
+	logger  logger.Logger
+	cancel  context.CancelFunc
+}
+
+// update stops watching the current directory, and watches the configured one when watching is enabled.
+func (w *templateWatcher) update(cfg config.TemplatesConfig) {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+
+	if cfg.Dir == "" || !cfg.Watch {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	w.cancel = cancel
+
+	go func() {
+		if err := w.catalog.Watch(ctx, cfg.Dir, w.logger); err != nil {
+			w.logger.Error("failed to watch greeting templates", zap.Error(err))
+		}
+	}()
+}
+
+// newAuditLogger creates the audit logger for the configured sink. It returns nil when auditing is disabled.
+func newAuditLogger(cfg config.AuditConfig) (*audit.Logger, error) {
+	switch cfg.Sink {
+	case "stdout":
+		return audit.NewLogger(os.Stdout), nil
+	case "file":
+		return audit.OpenFile(cfg.File)
+	default:
+		return nil, nil
+	}
+}
+
+// newStore opens the greeting history store for the configured driver. It returns nil when history is disabled.
+func newStore(cfg config.StoreConfig) (store.Store, error) {
+	switch cfg.Driver {
+	case "memory":
+		return store.NewMemoryStore(cfg.MemoryCapacity), nil
+	case "bolt":
+		s, err := store.OpenBoltStore(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newIdempotencyStore opens the idempotency store for the configured driver. It returns nil when idempotency
+// keys are disabled.
+func newIdempotencyStore(cfg config.IdempotencyConfig) (idempotency.Store, error) {
+	switch cfg.Driver {
+	case "memory":
+		return idempotency.NewMemoryStore(cfg.MemoryCapacity), nil
+	case "bolt":
+		s, err := idempotency.OpenBoltStore(cfg.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	default:
+		return nil, nil
+	}
+}
+
+// deadlineOptions converts the deadline configuration into deadline interceptor options.
+func deadlineOptions(cfg config.DeadlineConfig) []middleware.DeadlineOption {
+	opts := []middleware.DeadlineOption{
+		middleware.WithDefaultDeadlinePolicy(middleware.DeadlinePolicy{
+			Default:    cfg.Default,
+			Max:        cfg.Max,
+			StreamIdle: cfg.StreamIdle,
+			StreamMax:  cfg.StreamMax,
+		}),
+	}
+
+	for method, m := range cfg.Methods {
+		opts = append(opts, middleware.WithMethodDeadlinePolicy(method, middleware.DeadlinePolicy{
+			Default:    m.Default,
+			Max:        m.Max,
+			StreamIdle: m.StreamIdle,
+			StreamMax:  m.StreamMax,
+		}))
+	}
+
+	return opts
+}
+
+// streamLimits converts the stream limits configuration into Greeter stream limits.
+func streamLimits(cfg config.StreamLimitsConfig) greeter.StreamLimits {
+	return greeter.StreamLimits{
+		MaxMessages: cfg.MaxMessages,
+		MaxBytes:    cfg.MaxBytes.Int(),
+	}
+}
+
+// streamGreetingsLimits converts the StreamGreetings configuration into Greeter StreamGreetings limits.
+func streamGreetingsLimits(cfg config.StreamGreetingsConfig) greeter.StreamGreetingsLimits {
+	return greeter.StreamGreetingsLimits{
+		DefaultCount: cfg.DefaultCount,
+		MaxCount:     cfg.MaxCount,
+		MaxInterval:  cfg.MaxInterval,
+	}
+}
+
+// cacheOptions converts the cache configuration into cache interceptor options.
+func cacheOptions(cfg config.CacheConfig) []middleware.CacheOption {
+	opts := []middleware.CacheOption{middleware.WithCacheMaxBytes(cfg.MaxBytes.Int())}
+
+	for method, m := range cfg.Methods {
+		opts = append(opts, middleware.WithCachedMethod(method, middleware.CachePolicy{
+			TTL:      m.TTL,
+			Metadata: m.Metadata,
+		}))
+	}
+
+	return opts
+}
+
+// featureFlagList converts the feature flag configuration into flags. Variants are sorted by name.
+func featureFlagList(cfg map[string]config.FlagConfig) []flags.Flag {
+	list := make([]flags.Flag, 0, len(cfg))
+
+	for name, f := range cfg {
+		flag := flags.Flag{
+			Name:       name,
+			Kind:       flags.Kind(f.Kind),
+			Enabled:    f.Enabled,
+			Percentage: f.Percentage,
+			Methods:    f.Methods,
+			Principals: f.Principals,
+		}
+
+		for _, variant := range slices.Sorted(maps.Keys(f.Variants)) {
+			flag.Variants = append(flag.Variants, flags.WeightedVariant{Name: variant, Weight: f.Variants[variant]})
+		}
+
+		list = append(list, flag)
+	}
+
+	return list
+}
@@ -0,0 +1,197 @@
+// Package audit provides an append-only, hash-chained audit log.
+//
+// Every record carries the hash of the record written before it, so removing, reordering or editing
+// a record breaks the chain and is detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// genesisHash is the previous hash of the first record in a chain.
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// ErrChainBroken is returned by Verify when a record does not match the chain.
+var ErrChainBroken = errors.New("audit chain broken")
+
+// Record is a single audit log entry.
+type Record struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Code      string    `json:"code"`
+	Peer      string    `json:"peer"`
+	Principal string    `json:"principal"`
+	RequestID string    `json:"request_id,omitempty"`
+	Duration  float64   `json:"duration_seconds"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// computeHash returns the hash of the record, excluding its own Hash field.
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	sum := sha256.Sum256(bs)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Logger writes hash-chained audit records to a sink. It is safe for concurrent use.
+type Logger struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closer   io.Closer
+	seq      uint64
+	prevHash string
+	now      func() time.Time
+}
+
+// NewLogger creates a Logger writing a new chain to w, one JSON record per line.
+func NewLogger(w io.Writer) *Logger {
+	return &Logger{w: w, prevHash: genesisHash, now: time.Now}
+}
+
+// OpenFile opens, or creates, an audit log file for appending. If the file already holds records,
+// the chain is verified and continued from its last record.
+func OpenFile(path string) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	last, _, err := verify(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("refusing to append to audit log %s: %w", path, err)
+	}
+
+	l := NewLogger(f)
+	l.closer = f
+
+	if last != nil {
+		l.seq = last.Seq
+		l.prevHash = last.Hash
+	}
+
+	return l, nil
+}
+
+// Write completes the record with its sequence number and hashes, and appends it to the sink.
+// The record time is set when it is zero.
+func (l *Logger) Write(r Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.Time.IsZero() {
+		r.Time = l.now()
+	}
+
+	r.Time = r.Time.UTC()
+	r.Seq = l.seq + 1
+	r.PrevHash = l.prevHash
+
+	hash, err := r.computeHash()
+	if err != nil {
+		return err
+	}
+
+	r.Hash = hash
+
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	if _, err := l.w.Write(append(bs, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	l.seq = r.Seq
+	l.prevHash = r.Hash
+
+	return nil
+}
+
+// Close closes the underlying sink if the Logger owns it.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+
+	return l.closer.Close()
+}
+
+// Verify reads a chain of records from r and checks every hash and link.
+// It returns the number of valid records, and wraps ErrChainBroken on the first invalid one.
+func Verify(r io.Reader) (int, error) {
+	_, n, err := verify(r)
+	return n, err
+}
+
+func verify(r io.Reader) (*Record, int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var (
+		last *Record
+		n    int
+	)
+
+	prevHash := genesisHash
+
+	for scanner.Scan() {
+		line := n + 1
+
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return last, n, fmt.Errorf("%w: line %d: invalid record: %v", ErrChainBroken, line, err)
+		}
+
+		if rec.Seq != uint64(line) {
+			return last, n, fmt.Errorf("%w: line %d: expected seq %d, got %d", ErrChainBroken, line, line, rec.Seq)
+		}
+
+		if rec.PrevHash != prevHash {
+			return last, n, fmt.Errorf("%w: line %d: previous hash mismatch", ErrChainBroken, line)
+		}
+
+		hash, err := rec.computeHash()
+		if err != nil {
+			return last, n, err
+		}
+
+		if rec.Hash != hash {
+			return last, n, fmt.Errorf("%w: line %d: record hash mismatch", ErrChainBroken, line)
+		}
+
+		prevHash = rec.Hash
+		last = &rec
+		n++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return last, n, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return last, n, nil
+}
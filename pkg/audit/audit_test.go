@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRecords(t *testing.T, l *Logger, methods ...string) {
+	t.Helper()
+
+	for _, m := range methods {
+		require.NoError(t, l.Write(Record{Method: m, Code: "OK", Peer: "127.0.0.1:1234", Principal: "anonymous"}))
+	}
+}
+
+func TestLogger_WriteAndVerify(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := NewLogger(&buf)
+	writeRecords(t, l, "/greeter.Greeter/SayHello", "/greeter.Greeter/Chat", "/greeter.Greeter/SayHello")
+
+	n, err := Verify(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(lines []string) []string
+	}{
+		{
+			name: "edited record",
+			tamper: func(lines []string) []string {
+				lines[1] = strings.Replace(lines[1], `"code":"OK"`, `"code":"PermissionDenied"`, 1)
+				return lines
+			},
+		},
+		{
+			name: "removed record",
+			tamper: func(lines []string) []string {
+				return append(lines[:1], lines[2:]...)
+			},
+		},
+		{
+			name: "reordered records",
+			tamper: func(lines []string) []string {
+				lines[0], lines[1] = lines[1], lines[0]
+				return lines
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			l := NewLogger(&buf)
+			writeRecords(t, l, "/a", "/b", "/c")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			tampered := strings.Join(tt.tamper(lines), "\n")
+
+			_, err := Verify(strings.NewReader(tampered))
+			require.ErrorIs(t, err, ErrChainBroken)
+		})
+	}
+}
+
+func TestOpenFile_ContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+
+	l, err := OpenFile(path)
+	require.NoError(t, err)
+	writeRecords(t, l, "/a", "/b")
+	require.NoError(t, l.Close())
+
+	l, err = OpenFile(path)
+	require.NoError(t, err)
+	writeRecords(t, l, "/c")
+	require.NoError(t, l.Close())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Logf("failed to close audit log: %v", err)
+		}
+	}()
+
+	n, err := Verify(f)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+}
+
+func TestOpenFile_RejectsBrokenChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"seq":7}`+"\n"), 0600))
+
+	_, err := OpenFile(path)
+	require.ErrorIs(t, err, ErrChainBroken)
+}
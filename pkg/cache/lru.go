@@ -0,0 +1,99 @@
+// Package cache provides an in-memory LRU cache bounded by the total size of its entries, with a TTL per entry.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a least recently used cache. It is safe for concurrent use.
+type LRU[V any] struct {
+	mu       sync.Mutex
+	maxBytes int
+	bytes    int
+	order    *list.List // of *entry[V], most recently used first
+	entries  map[string]*list.Element
+	now      func() time.Time
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	size    int
+	stored  time.Time
+	expires time.Time
+}
+
+// NewLRU creates an LRU holding entries of at most maxBytes in total. The least recently used entries are
+// evicted to make room for new ones.
+func NewLRU[V any](maxBytes int) *LRU[V] {
+	return &LRU[V]{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get returns the value of the key and its age, unless it is missing or expired.
+func (c *LRU[V]) Get(key string) (V, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+
+	e, ok := c.entries[key]
+	if !ok {
+		return zero, 0, false
+	}
+
+	ent := e.Value.(*entry[V])
+
+	now := c.now()
+	if !now.Before(ent.expires) {
+		c.remove(e)
+		return zero, 0, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return ent.value, now.Sub(ent.stored), true
+}
+
+// Add stores the value of the key for the TTL, replacing any previous value. size is the size of the value in
+// bytes; values larger than the cache are not stored.
+func (c *LRU[V]) Add(key string, value V, size int, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+
+	if size > c.maxBytes {
+		return
+	}
+
+	now := c.now()
+	c.entries[key] = c.order.PushFront(&entry[V]{key: key, value: value, size: size, stored: now, expires: now.Add(ttl)})
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of entries, including expired entries not yet evicted.
+func (c *LRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU[V]) remove(e *list.Element) {
+	ent := c.order.Remove(e).(*entry[V])
+	delete(c.entries, ent.key)
+	c.bytes -= ent.size
+}
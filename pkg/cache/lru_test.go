@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := NewLRU[string](10)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", "A", 4, time.Minute)
+	c.Add("b", "B", 4, time.Minute)
+
+	now = now.Add(time.Second)
+
+	// Reading a makes b the least recently used
+	v, age, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "A", v)
+	assert.Equal(t, time.Second, age)
+
+	c.Add("c", "C", 4, time.Minute)
+
+	_, _, ok = c.Get("b")
+	assert.False(t, ok, "b should be evicted")
+	assert.Equal(t, 2, c.Len())
+
+	// Values larger than the cache are not stored, and drop the previous value of the key
+	c.Add("a", "huge", 11, time.Minute)
+
+	_, _, ok = c.Get("a")
+	assert.False(t, ok)
+
+	// Expired values are missing
+	now = now.Add(time.Minute)
+
+	_, _, ok = c.Get("c")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
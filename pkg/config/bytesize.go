@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ByteSize is a size in bytes, set from a number of bytes or a size with a unit: 512KiB, 64MiB, 1GB or 1.5GiB.
+// Units are case-insensitive; KB, MB, GB and TB are powers of 1000, KiB, MiB, GiB and TiB powers of 1024.
+type ByteSize int64
+
+// Byte size units.
+const (
+	Byte ByteSize = 1
+	KB   ByteSize = 1000 * Byte
+	MB   ByteSize = 1000 * KB
+	GB   ByteSize = 1000 * MB
+	TB   ByteSize = 1000 * GB
+	KiB  ByteSize = 1 << 10
+	MiB  ByteSize = 1 << 20
+	GiB  ByteSize = 1 << 30
+	TiB  ByteSize = 1 << 40
+)
+
+// byteSizeUnits maps the lower case units to their size. Ki, Mi, Gi and Ti are the Kubernetes spelling of the
+// binary units.
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"kb":  KB,
+	"kib": KiB,
+	"ki":  KiB,
+	"mb":  MB,
+	"mib": MiB,
+	"mi":  MiB,
+	"gb":  GB,
+	"gib": GiB,
+	"gi":  GiB,
+	"tb":  TB,
+	"tib": TiB,
+	"ti":  TiB,
+}
+
+// ParseByteSize parses a number of bytes or a size with a unit, e.g. 64MiB.
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.TrimSpace(s)
+	i := strings.IndexFunc(value, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+
+	number, unit := value, ""
+	if i >= 0 {
+		number, unit = value[:i], strings.TrimSpace(value[i:])
+	}
+
+	multiplier, ok := byteSizeUnits[strings.ToLower(unit)]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	size := n * float64(multiplier)
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("byte size %q is too large", s)
+	}
+
+	return ByteSize(size), nil
+}
+
+// Int returns the size as an int, for the APIs taking sizes as ints.
+func (b ByteSize) Int() int {
+	return int(b)
+}
+
+// String returns the size with the unit giving the smallest whole number, e.g. 64MiB or 128MB, or in bytes.
+func (b ByteSize) String() string {
+	n, unit := int64(b), ""
+
+	for _, u := range []struct {
+		size ByteSize
+		name string
+	}{{KB, "KB"}, {KiB, "KiB"}, {MB, "MB"}, {MiB, "MiB"}, {GB, "GB"}, {GiB, "GiB"}, {TB, "TB"}, {TiB, "TiB"}} {
+		if b != 0 && b%u.size == 0 && int64(b/u.size) < n {
+			n, unit = int64(b/u.size), u.name
+		}
+	}
+
+	return strconv.FormatInt(n, 10) + unit
+}
+
+// MarshalText writes the size like String.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText parses the size like ParseByteSize.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+
+	*b = size
+
+	return nil
+}
@@ -1,57 +1,261 @@
 // Package config provides a configuration loading and validation functionality.
+//
+// Values are layered in increasing order of precedence: the `default` struct tags, a configuration file, the
+// file of the selected profile, environment variables and command line flags. Secret values may reference
+// their value instead, e.g. file:///run/secrets/jwt, resolved when loading.
 package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/creasty/defaults"
 	validator "github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	yaml "gopkg.in/yaml.v2"
 )
 
-// LoadConfig loads the configuration from a os environment variables.
-func LoadConfig[T any](c T) (T, error) {
+const (
+	// FileEnv is the environment variable selecting the configuration file when no --config flag is given. It is
+	// prefixed like every other variable, e.g. GREETER_CONFIG_FILE.
+	FileEnv = "CONFIG_FILE"
+	// ProfileEnv is the environment variable selecting the profile when no --profile flag is given.
+	ProfileEnv = "CONFIG_PROFILE"
+)
+
+// Options configures LoadConfig. The zero value reads the defaults and unprefixed environment variables only.
+type Options struct {
+	// EnvPrefix prefixes the environment variables, e.g. GREETER for GREETER_SERVER_PORT, so that the
+	// configurations of several services loaded by one binary do not collide.
+	EnvPrefix string
+	// File is the configuration file read when neither the --config flag nor CONFIG_FILE is set. The format is
+	// taken from the extension: .yaml, .yml, .json or .toml.
+	File string
+	// Profile is the profile, e.g. dev, staging or prod, used when neither the --profile flag nor CONFIG_PROFILE
+	// is set. The profile file sits next to the configuration file, with the profile before the extension:
+	// config.yaml is overridden by config.prod.yaml. A missing profile file is skipped.
+	Profile string
+	// Args are the command line flags: --config and --profile select the configuration file and profile, and
+	// every configuration key is a flag, e.g. --server.port=8080. Positional arguments are ignored.
+	Args []string
+	// Sources, when not nil, records the origin of every configuration value.
+	Sources Sources
+	// Resolvers resolve the references of Secret values by scheme. When nil, file:// and env:// references are
+	// resolved by FileResolver and EnvResolver.
+	Resolvers map[string]Resolver
+}
+
+// Option configures LoadConfig.
+type Option func(*Options)
+
+// WithOptions replaces the options set so far.
+func WithOptions(o Options) Option {
+	return func(opts *Options) {
+		*opts = o
+	}
+}
+
+// WithEnvPrefix sets Options.EnvPrefix.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *Options) {
+		o.EnvPrefix = prefix
+	}
+}
+
+// WithFile sets Options.File.
+func WithFile(path string) Option {
+	return func(o *Options) {
+		o.File = path
+	}
+}
+
+// WithProfile sets Options.Profile.
+func WithProfile(name string) Option {
+	return func(o *Options) {
+		o.Profile = name
+	}
+}
+
+// WithArgs sets Options.Args.
+func WithArgs(args []string) Option {
+	return func(o *Options) {
+		o.Args = args
+	}
+}
+
+// WithSources sets Options.Sources.
+func WithSources(sources Sources) Option {
+	return func(o *Options) {
+		o.Sources = sources
+	}
+}
+
+// LoadConfig loads the configuration from defaults, configuration files, environment variables and command line
+// flags, then validates it. Invalid values are reported by a *ValidationError. Every call uses its own viper
+// instance, so loads are independent and safe to run concurrently.
+func LoadConfig[T any](c T, opts ...Option) (T, error) {
+	c, _, err := load(c, newOptions(opts))
+
+	return c, err
+}
+
+func newOptions(opts []Option) Options {
+	o := Options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// load loads the configuration like LoadConfig, and also returns the paths of the configuration file and of the
+// profile file, whether or not the latter exists.
+func load[T any](c T, o Options) (T, []string, error) {
+	var files []string
+
 	err := SetDefault(c)
 	if err != nil {
-		return c, err
+		return c, files, fmt.Errorf("failed to set the default values: %w", err)
 	}
 
 	bs, e := yaml.Marshal(c)
 	if e != nil {
-		return c, errors.New("error marshalling config")
+		return c, files, fmt.Errorf("failed to marshal the default values: %w", e)
 	}
 
-	viper.SetConfigType("yaml")
-	viper.AutomaticEnv()
+	defaultValues := viper.New()
+	defaultValues.SetConfigType("yaml")
 
-	if e = viper.ReadConfig(bytes.NewBuffer(bs)); e != nil {
-		return c, errors.New("error reading config")
+	if e = defaultValues.ReadConfig(bytes.NewBuffer(bs)); e != nil {
+		return c, files, fmt.Errorf("failed to read the default values: %w", e)
 	}
 
-	bindKeys()
+	keys, maps := configKeys(reflect.TypeOf(c))
+	env := envNamer(o.EnvPrefix)
+	v := viper.New()
 
-	e = viper.Unmarshal(c)
+	for _, key := range defaultValues.AllKeys() {
+		if !underMap(key, maps) {
+			v.SetDefault(key, defaultValues.Get(key))
+		}
+	}
+
+	flags, err := parseFlags(keys, env, o.Args)
+	if err != nil {
+		return c, files, err
+	}
+
+	file := firstNonEmpty(flagValue(flags, "config"), os.Getenv(env(FileEnv)), o.File)
+	profile := firstNonEmpty(flagValue(flags, "profile"), os.Getenv(env(ProfileEnv)), o.Profile)
+
+	if file != "" {
+		files = append(files, file)
+		if profile != "" {
+			files = append(files, profileFile(file, profile))
+		}
+	}
+
+	layers, err := readFiles(file, profile)
+	if err != nil {
+		return c, files, err
+	}
+
+	for _, l := range layers {
+		settings := l.values.AllSettings()
+		for _, m := range maps {
+			removeKey(settings, m.name)
+		}
+
+		if err := v.MergeConfigMap(settings); err != nil {
+			return c, files, fmt.Errorf("failed to merge config file %s: %w", l.path, err)
+		}
+	}
+
+	bindKeys(v, keys, env)
+
+	for _, key := range keys {
+		if err := v.BindPFlag(key.name, flags.Lookup(key.name)); err != nil {
+			return c, files, fmt.Errorf("failed to bind flag --%s: %w", key.name, err)
+		}
+	}
+
+	resolvers := o.Resolvers
+	if resolvers == nil {
+		resolvers = defaultResolvers()
+	}
+
+	hook := decodeHook(resolvers)
+
+	e = v.Unmarshal(c, func(dc *mapstructure.DecoderConfig) {
+		dc.DecodeHook = hook
+	})
 	if e != nil {
-		return c, errors.New("error unmarshalling config")
+		return c, files, fmt.Errorf("failed to unmarshal config: %w", e)
+	}
+
+	for _, m := range maps {
+		settings, err := mapSettings(m, layers, env)
+		if err != nil {
+			return c, files, err
+		}
+
+		if err := decodeMap(c, m, settings, hook); err != nil {
+			return c, files, err
+		}
+	}
+
+	if o.Sources != nil {
+		recordSources(o.Sources, v.AllKeys(), maps, layers, flags, env)
 	}
 
 	validator := validator.New()
+	validator.RegisterCustomTypeFunc(secretValue, Secret{})
+
 	if e = validator.Struct(c); e != nil {
-		return c, e
+		return c, files, validationError(e, env, maps)
 	}
 
-	return c, nil
+	return c, files, nil
 }
 
 // Viper doesn't marshall environment variables automatically. It requires `bind` to be called for every param.
 // We are binding the keys explicitly here.
 // Issue - https://github.com/spf13/viper/issues/522
-func bindKeys() {
-	for _, key := range viper.AllKeys() {
-		_ = viper.BindEnv(key, strings.ToUpper(strings.ReplaceAll(key, ".", "_")))
+func bindKeys(v *viper.Viper, keys []configKey, env func(string) string) {
+	for _, key := range keys {
+		_ = v.BindEnv(key.name, env(key.name))
+	}
+}
+
+// underMap reports whether a key is a map key or below one.
+func underMap(key string, maps []configKey) bool {
+	for _, m := range maps {
+		if m.under(key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envNamer returns the function naming the environment variable of a configuration key, e.g. SERVER_PORT for
+// server.port, or GREETER_SERVER_PORT with the GREETER prefix.
+func envNamer(prefix string) func(key string) string {
+	if prefix != "" {
+		prefix = strings.ToUpper(strings.TrimSuffix(prefix, "_")) + "_"
+	}
+
+	return func(key string) string {
+		return prefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
 	}
 }
 
@@ -63,3 +267,161 @@ func SetDefault[T any](c T) error {
 
 	return nil
 }
+
+// fileLayer is a configuration file read into its own instance.
+type fileLayer struct {
+	layer  Layer
+	path   string
+	values *viper.Viper
+	// raw holds the settings of the file with the case of their keys, which viper lowers.
+	raw map[string]interface{}
+}
+
+// readFiles reads the configuration file and the file of the profile, in increasing order of precedence.
+func readFiles(file, profile string) ([]fileLayer, error) {
+	if file == "" {
+		if profile != "" {
+			return nil, fmt.Errorf("profile %q needs a configuration file", profile)
+		}
+
+		return nil, nil
+	}
+
+	base, err := readFile(LayerFile, file)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := []fileLayer{base}
+
+	if profile == "" {
+		return layers, nil
+	}
+
+	path := profileFile(file, profile)
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return layers, nil
+	}
+
+	l, err := readFile(LayerProfile, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(layers, l), nil
+}
+
+// profileFile returns the path of the file of a profile: config.yaml becomes config.prod.yaml.
+func profileFile(file, profile string) string {
+	ext := filepath.Ext(file)
+
+	return strings.TrimSuffix(file, ext) + "." + profile + ext
+}
+
+func readFile(layer Layer, path string) (fileLayer, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+
+	if err := v.ReadInConfig(); err != nil {
+		return fileLayer{}, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	raw, err := readRaw(path)
+	if err != nil {
+		return fileLayer{}, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	return fileLayer{layer: layer, path: path, values: v, raw: raw}, nil
+}
+
+// readRaw reads a configuration file into settings keeping the case of their keys. The format is taken from
+// the extension, like viper does.
+func readRaw(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")) {
+	case "json":
+		err = json.Unmarshal(data, &raw)
+	case "toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		var values map[interface{}]interface{}
+		if err = yaml.Unmarshal(data, &values); err == nil {
+			raw, _ = stringKeys(values).(map[string]interface{})
+		}
+	}
+
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	return raw, err
+}
+
+// stringKeys converts the maps decoded by yaml.v2, keyed by interface{}, into maps keyed by string.
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = stringKeys(e)
+		}
+
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = stringKeys(e)
+		}
+
+		return v
+	default:
+		return value
+	}
+}
+
+// parseFlags parses the --config and --profile flags, and a flag per configuration key. Boolean keys are set
+// without a value, e.g. --app.logtofile, and lists are comma-separated.
+func parseFlags(keys []configKey, env func(string) string, args []string) (*pflag.FlagSet, error) {
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.String("config", "", "configuration file, overriding "+env(FileEnv))
+	flags.String("profile", "", "configuration profile, overriding "+env(ProfileEnv))
+
+	for _, key := range keys {
+		if key.isBool() {
+			flags.Bool(key.name, false, "overrides "+env(key.name))
+		} else {
+			flags.String(key.name, "", "overrides "+env(key.name))
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("invalid command line flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+func flagValue(flags *pflag.FlagSet, name string) string {
+	f := flags.Lookup(name)
+	if f == nil || !f.Changed {
+		return ""
+	}
+
+	return f.Value.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
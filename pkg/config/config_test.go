@@ -2,8 +2,13 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	validator "github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -92,7 +97,438 @@ func TestLoadConfig_ValidationError(t *testing.T) {
 	// Load the configuration and expect an error
 	_, err = LoadConfig(cfg)
 
-	// Assert that there was a validation error
+	// Assert that there was a validation error naming the key, the variable and the rule
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "Field validation for 'Port' failed on the 'numeric' tag")
+	assert.EqualError(t, err, `invalid configuration: server.port (SERVER_PORT): violates "numeric"`)
+}
+
+// InvalidConfig defines a configuration with several validated values, including map elements and secrets.
+type InvalidConfig struct {
+	Server  TestServerConfig
+	Audit   InvalidAuditConfig
+	Methods map[string]InvalidMethodConfig `validate:"dive"`
+}
+
+// InvalidAuditConfig defines values validated with parameterized rules.
+type InvalidAuditConfig struct {
+	Sink  string `default:"none" validate:"oneof=none stdout file"`
+	Key   Secret `validate:"omitempty,min=8"`
+	Limit int    `default:"1" validate:"gt=0"`
+}
+
+// InvalidMethodConfig defines the values of a map element.
+type InvalidMethodConfig struct {
+	TTL time.Duration `validate:"gt=0"`
+}
+
+func TestLoadConfig_ValidationErrors(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yaml", `
+methods:
+  /greeter.Greeter/GetGreeting:
+    ttl: 0s
+`)
+
+	t.Setenv("APP_SERVER_PORT", "http")
+	t.Setenv("APP_AUDIT_SINK", "syslog")
+	t.Setenv("APP_AUDIT_KEY", "short")
+
+	_, err := LoadConfig(&InvalidConfig{}, WithEnvPrefix("app"), WithFile(file))
+
+	var ve *ValidationError
+	require.ErrorAs(t, err, &ve)
+	assert.Equal(t, []FieldError{
+		{Key: "server.port", Env: "APP_SERVER_PORT", Rule: "numeric"},
+		{Key: "audit.sink", Env: "APP_AUDIT_SINK", Rule: "oneof=none stdout file"},
+		{Key: "audit.key", Env: "APP_AUDIT_KEY", Rule: "min=8"},
+		{
+			Key:  "methods[/greeter.Greeter/GetGreeting].ttl",
+			Env:  "APP_METHODS__/greeter.Greeter/GetGreeting__TTL",
+			Rule: "gt=0",
+		},
+	}, ve.Fields)
+
+	assert.NotContains(t, err.Error(), "short", "values are not quoted, as they might be secrets")
+	assert.Contains(t, err.Error(), `audit.sink (APP_AUDIT_SINK): violates "oneof=none stdout file"`)
+
+	var errs validator.ValidationErrors
+	assert.ErrorAs(t, err, &errs, "the error of the validator is wrapped")
+}
+
+func TestFieldKey(t *testing.T) {
+	assert.Equal(t, "server.port", fieldKey("Config.Server.Port"))
+	assert.Equal(t, "cache.methods[/greeter.Greeter/GetGreeting].ttl",
+		fieldKey("Config.Cache.Methods[/greeter.Greeter/GetGreeting].TTL"))
+	assert.Equal(t, "debug.payloadmethods[0]", fieldKey("Config.Debug.PayloadMethods[0]"))
+}
+
+// LayeredConfig defines a configuration with a value per layer, to check their precedence.
+type LayeredConfig struct {
+	Server LayeredServerConfig
+}
+
+// LayeredServerConfig defines server-specific configuration for layering tests.
+type LayeredServerConfig struct {
+	Port    string `default:"8080"`
+	Host    string `default:"localhost"`
+	Name    string `default:"default"`
+	Region  string `default:"default"`
+	Verbose bool
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
+
+func TestLoadConfig_Files(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "yaml", file: "config.yaml", content: "server:\n  port: \"9000\"\n  host: example.com\n"},
+		{name: "json", file: "config.json", content: `{"server": {"port": "9000", "host": "example.com"}}`},
+		{name: "toml", file: "config.toml", content: "[server]\nport = \"9000\"\nhost = \"example.com\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, dir, tt.file, tt.content)
+
+			cfg, err := LoadConfig(&LayeredConfig{}, WithFile(path))
+			require.NoError(t, err)
+
+			assert.Equal(t, "9000", cfg.Server.Port)
+			assert.Equal(t, "example.com", cfg.Server.Host)
+			assert.Equal(t, "default", cfg.Server.Name)
+		})
+	}
+}
+
+func TestLoadConfig_Layers(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "config.yaml", "server:\n  port: \"9000\"\n  host: file\n  name: file\n  region: file\n")
+	profile := writeFile(t, dir, "config.prod.yaml", "server:\n  host: profile\n  name: profile\n")
+
+	t.Setenv(FileEnv, base)
+	t.Setenv(ProfileEnv, "prod")
+	t.Setenv("SERVER_NAME", "env")
+	t.Setenv("SERVER_REGION", "env")
+
+	sources := Sources{}
+
+	cfg, err := LoadConfig(&LayeredConfig{},
+		WithArgs([]string{"serve", "--server.region=flag", "--server.verbose"}),
+		WithSources(sources),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, LayeredServerConfig{
+		Port:    "9000",
+		Host:    "profile",
+		Name:    "env",
+		Region:  "flag",
+		Verbose: true,
+	}, cfg.Server)
+
+	assert.Equal(t, Sources{
+		"server.port":    {Layer: LayerFile, Name: base},
+		"server.host":    {Layer: LayerProfile, Name: profile},
+		"server.name":    {Layer: LayerEnv, Name: "SERVER_NAME"},
+		"server.region":  {Layer: LayerFlag, Name: "--server.region"},
+		"server.verbose": {Layer: LayerFlag, Name: "--server.verbose"},
+	}, sources)
+	assert.Equal(t, "env SERVER_NAME", sources["server.name"].String())
+}
+
+func TestLoadConfig_FileFlags(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.yaml", "server:\n  host: file\n")
+	writeFile(t, dir, "config.dev.yaml", "server:\n  host: dev\n")
+	other := writeFile(t, dir, "other.yaml", "server:\n  host: other\n")
+
+	// The flags take precedence over the environment, which takes precedence over the options
+	t.Setenv(FileEnv, other)
+
+	cfg, err := LoadConfig(&LayeredConfig{},
+		WithFile(filepath.Join(dir, "missing.yaml")),
+		WithArgs([]string{"--config", filepath.Join(dir, "config.yaml"), "--profile", "dev"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", cfg.Server.Host)
+
+	// A missing profile file is skipped
+	cfg, err = LoadConfig(&LayeredConfig{}, WithProfile("staging"))
+	require.NoError(t, err)
+	assert.Equal(t, "other", cfg.Server.Host)
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "missing file", opts: []Option{WithFile(filepath.Join(dir, "missing.yaml"))}},
+		{name: "unsupported format", opts: []Option{WithFile(writeFile(t, dir, "config.ini", "port=1"))}},
+		{name: "malformed file", opts: []Option{WithFile(writeFile(t, dir, "bad.yaml", "server: [\n"))}},
+		{name: "profile without file", opts: []Option{WithProfile("prod")}},
+		{name: "unknown flag", opts: []Option{WithArgs([]string{"--server.unknown=1"})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(&LayeredConfig{}, tt.opts...)
+			assert.Error(t, err)
+		})
+	}
+
+	// Decoding failures keep their cause
+	t.Setenv("SERVER_VERBOSE", "maybe")
+
+	_, err := LoadConfig(&LayeredConfig{})
+	assert.ErrorContains(t, err, "failed to unmarshal config")
+	assert.ErrorContains(t, err, "Verbose")
+}
+
+func TestLoadConfig_EnvPrefix(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("SERVER_PORT", "1111")
+	t.Setenv("GREETER_SERVER_PORT", "2222")
+	t.Setenv("GREETER_CONFIG_FILE", writeFile(t, dir, "config.yaml", "server:\n  host: greeter\n"))
+
+	sources := Sources{}
+
+	cfg, err := LoadConfig(&LayeredConfig{}, WithOptions(Options{EnvPrefix: "GREETER", Sources: sources}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "2222", cfg.Server.Port)
+	assert.Equal(t, "greeter", cfg.Server.Host)
+	assert.Equal(t, Origin{Layer: LayerEnv, Name: "GREETER_SERVER_PORT"}, sources["server.port"])
+
+	cfg, err = LoadConfig(&LayeredConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "1111", cfg.Server.Port)
+	assert.Equal(t, "localhost", cfg.Server.Host)
+}
+
+func TestLoadConfig_Isolated(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", "server:\n  host: file\n")
+
+	// The file and flags of a load do not leak into the next one
+	cfg, err := LoadConfig(&LayeredConfig{}, WithFile(file), WithArgs([]string{"--server.name=flag"}))
+	require.NoError(t, err)
+	assert.Equal(t, "file", cfg.Server.Host)
+	assert.Equal(t, "flag", cfg.Server.Name)
+
+	cfg, err = LoadConfig(&LayeredConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", cfg.Server.Host)
+	assert.Equal(t, "default", cfg.Server.Name)
+
+	// Concurrent loads are independent
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			name := strconv.Itoa(i)
+
+			cfg, err := LoadConfig(&LayeredConfig{}, WithArgs([]string{"--server.name", name}))
+			if assert.NoError(t, err) {
+				assert.Equal(t, name, cfg.Server.Name)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// TypedConfig defines a configuration with lists, maps, durations, byte sizes and nested structs.
+type TypedConfig struct {
+	Server  TypedServerConfig
+	Methods map[string]TypedMethodConfig `validate:"dive"`
+	Tracing *TypedTracingConfig
+}
+
+// TypedServerConfig defines values of every type settable from the environment.
+type TypedServerConfig struct {
+	Name     string
+	Origins  []string
+	Timeout  time.Duration `default:"5s"`
+	MaxBytes ByteSize      `default:"4MiB"`
+	Labels   map[string]string
+}
+
+// TypedMethodConfig defines the values of a map element.
+type TypedMethodConfig struct {
+	TTL      time.Duration `validate:"gt=0"`
+	Metadata []string
+	Labels   map[string]string
+}
+
+// TypedTracingConfig defines a nested struct without default values.
+type TypedTracingConfig struct {
+	Exporter TypedExporterConfig
+}
+
+// TypedExporterConfig defines a struct nested two levels deep.
+type TypedExporterConfig struct {
+	Endpoint string
+	Headers  []string
+}
+
+func TestLoadConfig_EnvTypes(t *testing.T) {
+	t.Setenv("SERVER_NAME", "greeter")
+	t.Setenv("SERVER_ORIGINS", " https://a.example.com, https://b.example.com ,")
+	t.Setenv("SERVER_TIMEOUT", "1m30s")
+	t.Setenv("SERVER_MAXBYTES", "64MiB")
+	t.Setenv("SERVER_LABELS__Team", "greeting")
+	t.Setenv("METHODS__/greeter.Greeter/GetGreeting__TTL", "30s")
+	t.Setenv("METHODS__/greeter.Greeter/GetGreeting__METADATA", "x-user-id, accept-language")
+	t.Setenv("TRACING_EXPORTER_ENDPOINT", "otel:4317")
+	t.Setenv("TRACING_EXPORTER_HEADERS", "a=1,b=2")
+
+	cfg, err := LoadConfig(&TypedConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "greeter", cfg.Server.Name)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.Server.Origins)
+	assert.Equal(t, 90*time.Second, cfg.Server.Timeout)
+	assert.Equal(t, 64*MiB, cfg.Server.MaxBytes)
+	assert.Equal(t, map[string]string{"Team": "greeting"}, cfg.Server.Labels)
+	assert.Equal(t, map[string]TypedMethodConfig{
+		"/greeter.Greeter/GetGreeting": {TTL: 30 * time.Second, Metadata: []string{"x-user-id", "accept-language"}},
+	}, cfg.Methods)
+	require.NotNil(t, cfg.Tracing)
+	assert.Equal(t, TypedExporterConfig{Endpoint: "otel:4317", Headers: []string{"a=1", "b=2"}}, cfg.Tracing.Exporter)
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, err := LoadConfig(&TypedConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 5*time.Second, cfg.Server.Timeout)
+	assert.Equal(t, 4*MiB, cfg.Server.MaxBytes)
+	assert.Empty(t, cfg.Server.Origins)
+	assert.Empty(t, cfg.Methods)
+}
+
+func TestLoadConfig_Maps(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yaml", `
+methods:
+  /greeter.Greeter/GetGreeting:
+    ttl: 10s
+    labels:
+      Tier.Name: gold
+  /greeter.Greeter/ListGreetings:
+    ttl: 1m
+`)
+
+	// The JSON object replaces the entries of the file, and entry variables replace those of the object
+	t.Setenv("METHODS", `{"/greeter.Greeter/ListGreetings":{"ttl":"2m","metadata":["x-request-id"]}}`)
+	t.Setenv("METHODS__/greeter.Greeter/ListGreetings__TTL", "3m")
+	t.Setenv("METHODS__/greeter.Greeter/GetGreeting__LABELS__Region", "eu")
+
+	sources := Sources{}
+
+	cfg, err := LoadConfig(&TypedConfig{}, WithFile(file), WithSources(sources))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]TypedMethodConfig{
+		"/greeter.Greeter/GetGreeting": {
+			TTL:    10 * time.Second,
+			Labels: map[string]string{"Tier.Name": "gold", "Region": "eu"},
+		},
+		"/greeter.Greeter/ListGreetings": {TTL: 3 * time.Minute, Metadata: []string{"x-request-id"}},
+	}, cfg.Methods)
+	assert.Equal(t, LayerEnv, sources["methods"].Layer)
+}
+
+func TestLoadConfig_TypeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		err  string
+	}{
+		{name: "invalid duration", env: map[string]string{"SERVER_TIMEOUT": "soon"}, err: "failed to unmarshal config"},
+		{name: "invalid byte size", env: map[string]string{"SERVER_MAXBYTES": "64XB"}, err: `invalid byte size "64XB"`},
+		{name: "invalid map object", env: map[string]string{"METHODS": "[1]"}, err: "METHODS must be a JSON object"},
+		{name: "missing map key", env: map[string]string{"METHODS____TTL": "1s"}, err: "missing map key"},
+		{name: "unknown map field", env: map[string]string{"METHODS__/x__COLOR": "red"}, err: "unknown field COLOR"},
+		{
+			name: "invalid map value",
+			env:  map[string]string{"METHODS__/x__TTL": "0s"},
+			err:  `methods[/x].ttl (METHODS__/x__TTL): violates "gt=0"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := LoadConfig(&TypedConfig{})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestLoadConfig_FlagTypes(t *testing.T) {
+	cfg, err := LoadConfig(&TypedConfig{}, WithArgs([]string{
+		"--server.origins=https://a.example.com, https://b.example.com",
+		"--server.timeout", "2s",
+		"--server.maxbytes=512KiB",
+		"--tracing.exporter.endpoint=otel:4317",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.Server.Origins)
+	assert.Equal(t, 2*time.Second, cfg.Server.Timeout)
+	assert.Equal(t, 512*KiB, cfg.Server.MaxBytes)
+	assert.Equal(t, "otel:4317", cfg.Tracing.Exporter.Endpoint)
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		size ByteSize
+		out  string
+	}{
+		{in: "0", size: 0, out: "0"},
+		{in: "1500", size: 1500, out: "1500"},
+		{in: "512KiB", size: 512 * KiB, out: "512KiB"},
+		{in: "64 mib", size: 64 * MiB, out: "64MiB"},
+		{in: "128MB", size: 128 * MB, out: "128MB"},
+		{in: "1.5GiB", size: 1536 * MiB, out: "1536MiB"},
+		{in: "2Gi", size: 2 * GiB, out: "2GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			size, err := ParseByteSize(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.size, size)
+			assert.Equal(t, tt.out, size.String())
+		})
+	}
+
+	for _, in := range []string{"", "MiB", "1..5KB", "12XB", "-1"} {
+		_, err := ParseByteSize(in)
+		assert.Error(t, err, in)
+	}
 }
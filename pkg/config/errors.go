@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	validator "github.com/go-playground/validator/v10"
+)
+
+// FieldError is a configuration value failing validation.
+type FieldError struct {
+	// Key is the configuration key of the value, e.g. "server.port". Map and slice elements are indexed, e.g.
+	// "cache.methods[/greeter.Greeter/GetGreeting].ttl".
+	Key string
+	// Env is the environment variable setting the value, e.g. SERVER_PORT or
+	// CACHE_METHODS__/greeter.Greeter/GetGreeting__TTL, or the list holding it for slice elements.
+	Env string
+	// Rule is the violated validation rule, e.g. "numeric" or "oneof=none stdout file".
+	Rule string
+}
+
+// Error describes the invalid value without quoting it, as it might be a secret.
+func (e FieldError) Error() string {
+	if e.Env == "" {
+		return fmt.Sprintf("%s: violates %q", e.Key, e.Rule)
+	}
+
+	return fmt.Sprintf("%s (%s): violates %q", e.Key, e.Env, e.Rule)
+}
+
+// ValidationError lists every invalid value of a configuration. It unwraps to the validator.ValidationErrors it
+// was built from.
+type ValidationError struct {
+	Fields []FieldError
+	cause  error
+}
+
+// Error lists the invalid values.
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Error()
+	}
+
+	return "invalid configuration: " + strings.Join(fields, "; ")
+}
+
+// Unwrap returns the error of the validator.
+func (e *ValidationError) Unwrap() error {
+	return e.cause
+}
+
+// validationError converts the error of the validator into a ValidationError naming the configuration keys and
+// environment variables of the invalid values.
+func validationError(err error, env func(string) string, maps []configKey) error {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	ve := &ValidationError{cause: err}
+
+	for _, fe := range errs {
+		f := FieldError{Key: fieldKey(fe.Namespace()), Rule: fe.Tag()}
+		if fe.Param() != "" {
+			f.Rule += "=" + fe.Param()
+		}
+
+		f.Env = fieldEnv(f.Key, env, maps)
+
+		ve.Fields = append(ve.Fields, f)
+	}
+
+	return ve
+}
+
+// fieldEnv returns the environment variable setting a key: map elements are set with their key between double
+// underscores, slice elements by their list.
+func fieldEnv(key string, env func(string) string, maps []configKey) string {
+	prefix, rest, indexed := strings.Cut(key, "[")
+	if !indexed {
+		return env(key)
+	}
+
+	index, rest, _ := strings.Cut(rest, "]")
+	if strings.Contains(rest, "[") {
+		return ""
+	}
+
+	if !slices.ContainsFunc(maps, func(m configKey) bool { return m.name == prefix }) {
+		return env(prefix)
+	}
+
+	name := env(prefix) + mapKeySeparator + index
+	if rest = strings.TrimPrefix(rest, "."); rest != "" {
+		name += mapKeySeparator + strings.ToUpper(strings.ReplaceAll(rest, ".", "_"))
+	}
+
+	return name
+}
+
+// fieldKey converts the namespace of a validated field, e.g. "Config.Server.Port", into its configuration key,
+// "server.port". Map keys and slice indexes are kept as they are.
+func fieldKey(namespace string) string {
+	// The namespace starts with the name of the configuration type
+	_, path, _ := strings.Cut(namespace, ".")
+
+	var (
+		b     strings.Builder
+		depth int
+	)
+
+	for _, r := range path {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth == 0:
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
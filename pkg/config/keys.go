@@ -0,0 +1,338 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// mapKeySeparator separates the key of a map entry in an environment variable name, e.g.
+// CACHE_METHODS__/greeter.Greeter/GetGreeting__TTL.
+const mapKeySeparator = "__"
+
+// configKey is a configuration key, e.g. "server.port", and the type of its value.
+type configKey struct {
+	name string
+	typ  reflect.Type
+}
+
+// configKeys walks the type of a configuration and returns the keys of its values, and the keys of its maps.
+// Nested structs, including pointers to structs, are walked whatever their value, so that every key can be set
+// without a default value. Maps are decoded separately, as viper would lower case and split their keys.
+func configKeys(t reflect.Type) (values, maps []configKey) {
+	var walk func(t reflect.Type, key string, seen map[reflect.Type]bool)
+
+	walk = func(t reflect.Type, key string, seen map[reflect.Type]bool) {
+		t = indirect(t)
+
+		switch {
+		case t.Kind() == reflect.Map:
+			maps = append(maps, configKey{name: key, typ: t})
+		case t.Kind() == reflect.Struct && hasExportedFields(t) && !seen[t]:
+			seen[t] = true
+			defer delete(seen, t)
+
+			for i := range t.NumField() {
+				f := t.Field(i)
+				if !f.IsExported() {
+					continue
+				}
+
+				name := strings.ToLower(f.Name)
+				if key != "" {
+					name = key + "." + name
+				}
+
+				walk(f.Type, name, seen)
+			}
+		case key != "":
+			values = append(values, configKey{name: key, typ: t})
+		}
+	}
+
+	walk(t, "", map[reflect.Type]bool{})
+
+	return values, maps
+}
+
+// isBool reports whether a key holds a boolean, set by a flag without a value.
+func (k configKey) isBool() bool {
+	return k.typ.Kind() == reflect.Bool
+}
+
+// under reports whether key is the key k or below it.
+func (k configKey) under(key string) bool {
+	return key == k.name || strings.HasPrefix(key, k.name+".")
+}
+
+// decodeHook converts the values read by viper, from files, environment variables and flags, into the types of
+// the configuration: secrets are resolved, types implementing encoding.TextUnmarshaler such as ByteSize parse
+// strings, durations are Go duration strings and lists are comma-separated.
+func decodeHook(resolvers map[string]Resolver) mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		secretHook(resolvers),
+		mapstructure.TextUnmarshallerHookFunc(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		stringToSliceHook,
+	)
+}
+
+// stringToSliceHook splits comma-separated lists, trimming the spaces around the elements and dropping the
+// empty ones, so that "a, b," is [a b].
+func stringToSliceHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to.Kind() != reflect.Slice {
+		return data, nil
+	}
+
+	values := []string{}
+
+	for _, v := range strings.Split(data.(string), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values, nil
+}
+
+// removeKey deletes a key, e.g. "cache.methods", from nested settings. Keys are matched case-insensitively.
+func removeKey(settings map[string]interface{}, key string) {
+	parts := strings.Split(key, ".")
+
+	for i, part := range parts {
+		k, ok := lookupFold(settings, part)
+		if !ok {
+			return
+		}
+
+		if i == len(parts)-1 {
+			delete(settings, k)
+			return
+		}
+
+		next, ok := settings[k].(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		settings = next
+	}
+}
+
+// lookupKey returns the value of a key, e.g. "cache.methods", in nested settings. Keys are matched
+// case-insensitively.
+func lookupKey(settings map[string]interface{}, key string) (interface{}, bool) {
+	var value interface{} = settings
+
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		k, ok := lookupFold(m, part)
+		if !ok {
+			return nil, false
+		}
+
+		value = m[k]
+	}
+
+	return value, true
+}
+
+func lookupFold(m map[string]interface{}, key string) (string, bool) {
+	if _, ok := m[key]; ok {
+		return key, true
+	}
+
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+// mergeSettings merges src into dst, recursively for nested settings. Other values of src replace those of dst.
+func mergeSettings(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+
+		if srcIsMap && dstIsMap {
+			mergeSettings(dstMap, srcMap)
+			continue
+		}
+
+		dst[k] = v
+	}
+}
+
+// mapSettings returns the entries of a map key set by the configuration files and environment variables, in
+// increasing order of precedence. Map keys keep their case.
+//
+// In the environment, the variable of the key sets the whole map as a JSON object, e.g.
+// CACHE_METHODS={"/greeter.Greeter/GetGreeting":{"ttl":"30s"}}, and each entry can be set on its own, with the map
+// key between double underscores: CACHE_METHODS__/greeter.Greeter/GetGreeting__TTL=30s.
+func mapSettings(k configKey, files []fileLayer, env func(string) string) (map[string]interface{}, error) {
+	settings := map[string]interface{}{}
+
+	for _, l := range files {
+		value, ok := lookupKey(l.raw, k.name)
+		if !ok || value == nil {
+			continue
+		}
+
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s in %s must be a map", k.name, l.path)
+		}
+
+		mergeSettings(settings, m)
+	}
+
+	name := env(k.name)
+
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &m); err != nil {
+			return nil, fmt.Errorf("environment variable %s must be a JSON object: %w", name, err)
+		}
+
+		mergeSettings(settings, m)
+	}
+
+	prefix := name + mapKeySeparator
+
+	for _, kv := range os.Environ() {
+		variable, value, _ := strings.Cut(kv, "=")
+
+		path, ok := strings.CutPrefix(variable, prefix)
+		if !ok {
+			continue
+		}
+
+		entry, err := envSetting(k.typ, path, value)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", variable, err)
+		}
+
+		mergeSettings(settings, entry.(map[string]interface{}))
+	}
+
+	return settings, nil
+}
+
+// envSetting nests the value of an environment variable along its path in a value of type t. The path of a
+// struct is made of its upper case field names separated by underscores, and the path of a map starts with the
+// map key, followed by double underscores when the element has a path too.
+func envSetting(t reflect.Type, path, value string) (interface{}, error) {
+	t = indirect(t)
+
+	if path == "" {
+		return value, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		key, rest, _ := strings.Cut(path, mapKeySeparator)
+		if key == "" {
+			return nil, errors.New("missing map key")
+		}
+
+		v, err := envSetting(t.Elem(), rest, value)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{key: v}, nil
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			name := strings.ToUpper(f.Name)
+
+			rest, ok := strings.CutPrefix(path, name)
+			if !ok {
+				continue
+			}
+
+			if rest != "" {
+				// Maps are separated from their key by double underscores, structs from their fields by one
+				sep := "_"
+				if indirect(f.Type).Kind() == reflect.Map {
+					sep = mapKeySeparator
+				}
+
+				if rest, ok = strings.CutPrefix(rest, sep); !ok {
+					continue
+				}
+			}
+
+			v, err := envSetting(f.Type, rest, value)
+			if err != nil {
+				return nil, err
+			}
+
+			return map[string]interface{}{strings.ToLower(f.Name): v}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown field %s", path)
+}
+
+// allocate dereferences a value, allocating nil pointers.
+func allocate(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// decodeMap decodes the settings of a map key into the map field of the configuration, adding to its default
+// entries.
+func decodeMap(c interface{}, k configKey, settings map[string]interface{}, hook mapstructure.DecodeHookFunc) error {
+	field := allocate(reflect.ValueOf(c))
+
+	for _, part := range strings.Split(k.name, ".") {
+		field = allocate(field.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, part) }))
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       hook,
+		WeaklyTypedInput: true,
+		Result:           field.Addr().Interface(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := decoder.Decode(settings); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", k.name, err)
+	}
+
+	return nil
+}
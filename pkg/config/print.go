@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Print writes a configuration as YAML, in the layout of the configuration files: keys are in lower case,
+// durations are written like 30s and secrets are redacted.
+func Print(w io.Writer, c any) error {
+	data, err := yaml.Marshal(printable(reflect.ValueOf(c)))
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// printable converts a configuration value into the value written by Print. Struct fields keep their order.
+func printable(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	switch v.Type() {
+	case secretType:
+		return v.Interface().(Secret).String()
+	case durationType:
+		return v.Interface().(time.Duration).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if !hasExportedFields(v.Type()) {
+			return v.Interface()
+		}
+
+		fields := yaml.MapSlice{}
+
+		for i := range v.NumField() {
+			if f := v.Type().Field(i); f.IsExported() {
+				fields = append(fields, yaml.MapItem{Key: strings.ToLower(f.Name), Value: printable(v.Field(i))})
+			}
+		}
+
+		return fields
+	case reflect.Map:
+		entries := yaml.MapSlice{}
+		for _, k := range v.MapKeys() {
+			entries = append(entries, yaml.MapItem{Key: fmt.Sprint(k.Interface()), Value: printable(v.MapIndex(k))})
+		}
+
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Key.(string) < entries[j].Key.(string) })
+
+		return entries
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = printable(v.Index(i))
+		}
+
+		return values
+	default:
+		return v.Interface()
+	}
+}
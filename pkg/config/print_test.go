@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// PrintedConfig defines a configuration with the kinds of values written by Print.
+type PrintedConfig struct {
+	Server   TestServerConfig
+	Token    Secret
+	Empty    Secret
+	Timeout  time.Duration
+	Enabled  bool
+	Names    []string
+	Methods  map[string]WatchedLimitsConfig
+	Optional *TestServerConfig
+}
+
+func TestPrint(t *testing.T) {
+	c := &PrintedConfig{
+		Server:  TestServerConfig{Port: "8080", Host: "localhost"},
+		Token:   NewSecret("s3cr3t"),
+		Timeout: 90 * time.Second,
+		Enabled: true,
+		Names:   []string{"a", "b"},
+		Methods: map[string]WatchedLimitsConfig{
+			"b": {Max: 2, Timeout: time.Second},
+			"a": {Max: 1, Methods: map[string]int{"x": 1}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, c))
+
+	assert.Equal(t, `server:
+  port: "8080"
+  host: localhost
+token: '[REDACTED]'
+empty: ""
+timeout: 1m30s
+enabled: true
+names:
+- a
+- b
+methods:
+  a:
+    max: 1
+    timeout: 0s
+    methods:
+      x: 1
+  b:
+    max: 2
+    timeout: 1s
+    methods: {}
+optional: null
+`, buf.String())
+}
+
+func TestPrint_LoadsBack(t *testing.T) {
+	t.Setenv("SERVER_PORT", "9090")
+
+	cfg, err := LoadConfig(&LayeredConfig{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, cfg))
+
+	file := writeFile(t, t.TempDir(), "printed.yaml", buf.String())
+	t.Setenv("SERVER_PORT", "")
+
+	printed, err := LoadConfig(&LayeredConfig{}, WithFile(file))
+	require.NoError(t, err)
+	assert.Equal(t, cfg, printed, "the printed configuration is a configuration file")
+}
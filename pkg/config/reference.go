@@ -0,0 +1,187 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// mapKeyPlaceholder stands for the keys of a map in the reference, e.g. cache.methods[<name>].ttl.
+const mapKeyPlaceholder = "<name>"
+
+var byteSizeType = reflect.TypeOf(ByteSize(0))
+
+// ReferenceEntry documents a configuration value: how to set it, its default value and its validation rules.
+type ReferenceEntry struct {
+	// Key is the configuration key, e.g. "server.port", also used as a flag, e.g. --server.port. The keys of
+	// map elements are written as cache.methods[<name>].ttl.
+	Key string `json:"key"`
+	// Env is the environment variable, e.g. SERVER_PORT or CACHE_METHODS__<name>__TTL.
+	Env string `json:"env"`
+	// Type describes the accepted values, e.g. "duration" or "list of string".
+	Type string `json:"type"`
+	// Default is the `default` struct tag.
+	Default string `json:"default,omitempty"`
+	// Validate is the `validate` struct tag.
+	Validate string `json:"validate,omitempty"`
+	// Description is the `desc` struct tag.
+	Description string `json:"description,omitempty"`
+}
+
+// Reference walks the type of a configuration and documents each of its values, in the order of the struct
+// fields. Maps are documented by their own entry, followed by the entries of their elements. The environment
+// variables take the prefix of the options, e.g. WithEnvPrefix.
+func Reference(c any, opts ...Option) []ReferenceEntry {
+	env := envNamer(newOptions(opts).EnvPrefix)
+
+	var entries []ReferenceEntry
+
+	walkReference(indirect(reflect.TypeOf(c)), "", "", &entries, env, map[reflect.Type]bool{})
+
+	return entries
+}
+
+// walkReference appends the entries of the fields of a struct. The variable of a key below a map is built from
+// the variable of the map, passed as mapEnv, instead of the key.
+func walkReference(
+	t reflect.Type, key, mapEnv string, entries *[]ReferenceEntry, env func(string) string, seen map[reflect.Type]bool,
+) {
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := strings.ToLower(f.Name)
+		fieldKey, fieldEnv := name, env(name)
+
+		if key != "" {
+			fieldKey, fieldEnv = key+"."+name, env(key+"."+name)
+		}
+
+		if mapEnv != "" {
+			fieldEnv = mapEnv + strings.ToUpper(name)
+		}
+
+		ft := indirect(f.Type)
+
+		if ft.Kind() == reflect.Struct && hasExportedFields(ft) && ft != secretType {
+			if !seen[ft] {
+				nested := mapEnv
+				if nested != "" {
+					nested = fieldEnv + "_"
+				}
+
+				walkReference(ft, fieldKey, nested, entries, env, seen)
+			}
+
+			continue
+		}
+
+		*entries = append(*entries, ReferenceEntry{
+			Key:         fieldKey,
+			Env:         fieldEnv,
+			Type:        typeName(ft),
+			Default:     f.Tag.Get("default"),
+			Validate:    f.Tag.Get("validate"),
+			Description: f.Tag.Get("desc"),
+		})
+
+		if ft.Kind() != reflect.Map {
+			continue
+		}
+
+		elem := indirect(ft.Elem())
+		elemKey := fieldKey + "[" + mapKeyPlaceholder + "]"
+		elemEnv := fieldEnv + mapKeySeparator + mapKeyPlaceholder
+
+		if elem.Kind() == reflect.Struct && hasExportedFields(elem) && elem != secretType && !seen[elem] {
+			walkReference(elem, elemKey, elemEnv+mapKeySeparator, entries, env, seen)
+		}
+	}
+}
+
+// typeName describes the values accepted for a type.
+func typeName(t reflect.Type) string {
+	t = indirect(t)
+
+	switch t {
+	case secretType:
+		return "secret"
+	case durationType:
+		return "duration"
+	case byteSizeType:
+		return "byte size"
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		elem := indirect(t.Elem())
+		if elem.Kind() == reflect.Struct {
+			return "map of object"
+		}
+
+		return "map of " + typeName(elem)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// WriteMarkdown writes the reference as a Markdown table.
+func WriteMarkdown(w io.Writer, entries []ReferenceEntry) error {
+	var b strings.Builder
+
+	b.WriteString("| Key | Environment variable | Type | Default | Validation | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+
+	for _, e := range entries {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s | %s |\n",
+			e.Key, e.Env, e.Type, markdownCode(e.Default), markdownCode(e.Validate), markdownText(e.Description))
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	return "`" + strings.ReplaceAll(s, "|", `\|`) + "`"
+}
+
+func markdownText(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// WriteJSON writes the reference as an indented JSON array.
+func WriteJSON(w io.Writer, entries []ReferenceEntry) error {
+	return writeIndentedJSON(w, entries)
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+
+	return err
+}
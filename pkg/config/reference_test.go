@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DocumentedConfig defines a configuration documented with desc tags.
+type DocumentedConfig struct {
+	Server  TestServerConfig
+	Auth    SecretAuthConfig
+	Methods map[string]DocumentedMethodConfig `validate:"dive" desc:"Methods by name"`
+	Tracing *TypedTracingConfig
+}
+
+// DocumentedMethodConfig defines the values of a map element.
+type DocumentedMethodConfig struct {
+	Limit    ByteSize          `default:"1KiB" validate:"gte=0" desc:"Size limit | per call"`
+	Metadata []string          `validate:"max=4"`
+	Labels   map[string]string `desc:"Labels by name"`
+	Retry    DocumentedRetryConfig
+}
+
+// DocumentedRetryConfig defines a struct nested in a map element.
+type DocumentedRetryConfig struct {
+	Attempts int `default:"3" validate:"gte=1,lte=5" desc:"Attempts"`
+}
+
+func TestReference(t *testing.T) {
+	entries := Reference(&DocumentedConfig{}, WithEnvPrefix("APP"))
+
+	assert.Equal(t, []ReferenceEntry{
+		{Key: "server.port", Env: "APP_SERVER_PORT", Type: "string", Default: "8080", Validate: "required,numeric"},
+		{Key: "server.host", Env: "APP_SERVER_HOST", Type: "string", Default: "localhost", Validate: "required"},
+		{Key: "auth.issuer", Env: "APP_AUTH_ISSUER", Type: "string", Default: "greeter"},
+		{Key: "auth.key", Env: "APP_AUTH_KEY", Type: "secret"},
+		{Key: "auth.token", Env: "APP_AUTH_TOKEN", Type: "secret", Validate: "required"},
+		{Key: "methods", Env: "APP_METHODS", Type: "map of object", Validate: "dive", Description: "Methods by name"},
+		{
+			Key: "methods[<name>].limit", Env: "APP_METHODS__<name>__LIMIT", Type: "byte size",
+			Default: "1KiB", Validate: "gte=0", Description: "Size limit | per call",
+		},
+		{Key: "methods[<name>].metadata", Env: "APP_METHODS__<name>__METADATA", Type: "list of string", Validate: "max=4"},
+		{
+			Key: "methods[<name>].labels", Env: "APP_METHODS__<name>__LABELS", Type: "map of string",
+			Description: "Labels by name",
+		},
+		{
+			Key: "methods[<name>].retry.attempts", Env: "APP_METHODS__<name>__RETRY_ATTEMPTS", Type: "integer",
+			Default: "3", Validate: "gte=1,lte=5", Description: "Attempts",
+		},
+		{Key: "tracing.exporter.endpoint", Env: "APP_TRACING_EXPORTER_ENDPOINT", Type: "string"},
+		{Key: "tracing.exporter.headers", Env: "APP_TRACING_EXPORTER_HEADERS", Type: "list of string"},
+	}, entries)
+}
+
+func TestReference_EnvSetsKeys(t *testing.T) {
+	// The documented variables set the documented keys
+	t.Setenv("APP_METHODS__/x__RETRY_ATTEMPTS", "4")
+	t.Setenv("APP_METHODS__/x__LABELS__Team", "greeting")
+	t.Setenv("APP_AUTH_TOKEN", "token")
+
+	cfg, err := LoadConfig(&DocumentedConfig{}, WithEnvPrefix("APP"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, cfg.Methods["/x"].Retry.Attempts)
+	assert.Equal(t, map[string]string{"Team": "greeting"}, cfg.Methods["/x"].Labels)
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var b bytes.Buffer
+
+	require.NoError(t, WriteMarkdown(&b, Reference(&DocumentedConfig{})[:7]))
+
+	lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+	require.Len(t, lines, 9)
+	assert.Equal(t, "| Key | Environment variable | Type | Default | Validation | Description |", string(lines[0]))
+	assert.Equal(t, "| `server.port` | `SERVER_PORT` | string | `8080` | `required,numeric` |  |", string(lines[2]))
+	assert.Equal(t,
+		"| `methods[<name>].limit` | `METHODS__<name>__LIMIT` | byte size | `1KiB` | `gte=0` | Size limit \\| per call |",
+		string(lines[8]))
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b bytes.Buffer
+
+	require.NoError(t, WriteJSON(&b, Reference(&DocumentedConfig{})[:1]))
+	assert.JSONEq(t, `[{"key":"server.port","env":"SERVER_PORT","type":"string","default":"8080",
+		"validate":"required,numeric"}]`, b.String())
+}
+
+func TestWriteJSONSchema(t *testing.T) {
+	var b bytes.Buffer
+
+	require.NoError(t, WriteJSONSchema(&b, &DocumentedConfig{}, "test"))
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(b.Bytes(), &schema))
+
+	assert.Equal(t, schemaDraft, schema["$schema"])
+	assert.Equal(t, "test", schema["title"])
+	assert.Equal(t, false, schema["additionalProperties"])
+
+	property := func(path ...string) map[string]any {
+		s := schema
+		for _, p := range path {
+			s = s[p].(map[string]any)
+		}
+
+		return s
+	}
+
+	port := property("properties", "server", "properties", "port")
+	assert.Equal(t, "8080", port["default"])
+	assert.EqualValues(t, 1, port["minLength"])
+	assert.NotEmpty(t, port["pattern"])
+
+	token := property("properties", "auth", "properties", "token")
+	assert.Equal(t, "string", token["type"])
+	assert.EqualValues(t, 1, token["minLength"])
+
+	method := property("properties", "methods", "additionalProperties", "properties")
+	assert.Equal(t, "1KiB", method["limit"].(map[string]any)["default"])
+	assert.EqualValues(t, 4, method["metadata"].(map[string]any)["maxItems"])
+
+	attempts := method["retry"].(map[string]any)["properties"].(map[string]any)["attempts"].(map[string]any)
+	assert.Equal(t, "integer", attempts["type"])
+	assert.EqualValues(t, 3, attempts["default"])
+	assert.EqualValues(t, 1, attempts["minimum"])
+	assert.EqualValues(t, 5, attempts["maximum"])
+	assert.Equal(t, "Attempts", attempts["description"])
+}
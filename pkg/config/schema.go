@@ -0,0 +1,200 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// schemaDraft is the JSON Schema dialect of the written schemas.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Patterns of the values parsed from strings.
+const (
+	durationPattern = `^(0|[-+]?(\d+(\.\d*)?|\.\d+)(ns|us|µs|ms|s|m|h))+$`
+	byteSizePattern = `^\s*\d+(\.\d*)?\s*([kKmMgGtT]([iI][bB]?|[bB])?|[bB])?\s*$`
+)
+
+// WriteJSONSchema writes a JSON Schema of the configuration files of a configuration, for the validation and
+// completion of editors. Keys are in lower case, descriptions come from the `desc` struct tags, defaults from
+// the `default` tags, and the `validate` rules with a JSON Schema equivalent are translated: required, oneof,
+// numeric, min, max, len, gt, gte, lt and lte.
+func WriteJSONSchema(w io.Writer, c any, title string) error {
+	schema := typeSchema(reflect.TypeOf(c), map[reflect.Type]bool{})
+	schema["$schema"] = schemaDraft
+	schema["title"] = title
+
+	return writeIndentedJSON(w, schema)
+}
+
+// typeSchema returns the schema of the values of a type.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	t = indirect(t)
+
+	switch t {
+	case secretType:
+		return map[string]any{
+			"type":        "string",
+			"description": "A literal value or a reference such as file:///run/secrets/key or env://KEY.",
+		}
+	case durationType:
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	case byteSizeType:
+		return map[string]any{
+			"type":    []string{"integer", "string"},
+			"minimum": 0,
+			"pattern": byteSizePattern,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return structSchema(t, seen)
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), seen)}
+	case reflect.Slice, reflect.Array:
+		// Lists are also accepted as comma-separated strings
+		return map[string]any{
+			"anyOf": []any{
+				map[string]any{"type": "array", "items": typeSchema(t.Elem(), seen)},
+				map[string]any{"type": "string"},
+			},
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema returns the schema of a struct, rejecting unknown keys so that typos are reported.
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	if seen[t] {
+		return map[string]any{"type": "object"}
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+
+	properties := map[string]any{}
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		schema := typeSchema(f.Type, seen)
+
+		if desc := f.Tag.Get("desc"); desc != "" {
+			schema["description"] = desc
+		}
+
+		if def, ok := f.Tag.Lookup("default"); ok {
+			schema["default"] = schemaDefault(indirect(f.Type), def)
+		}
+
+		applyRules(schema, indirect(f.Type), f.Tag.Get("validate"))
+
+		properties[strings.ToLower(f.Name)] = schema
+	}
+
+	return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+}
+
+// schemaDefault converts a `default` tag into a JSON value of the type of the field.
+func schemaDefault(t reflect.Type, def string) any {
+	if t == durationType || t == byteSizeType || t == secretType {
+		return def
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(def, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, err := strconv.ParseFloat(def, 64); err == nil {
+			return n
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(def); err == nil {
+			return b
+		}
+	}
+
+	return def
+}
+
+// applyRules translates the validation rules of a field into schema keywords. Rules after dive apply to the
+// elements, and rules without an equivalent, such as gtefield, are left to the validation of LoadConfig.
+func applyRules(schema map[string]any, t reflect.Type, rules string) {
+	numeric := t.Kind() != reflect.String && t != secretType &&
+		t.Kind() != reflect.Slice && t.Kind() != reflect.Map && t != byteSizeType && t != durationType
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "dive":
+			return
+		case "required":
+			if t.Kind() == reflect.String || t == secretType {
+				schema["minLength"] = 1
+			}
+		case "oneof":
+			schema["enum"] = strings.Fields(param)
+		case "numeric":
+			schema["pattern"] = `^[-+]?\d+(\.\d+)?$`
+		case "min", "max", "len":
+			applyLength(schema, t, name, param, numeric)
+		case "gt", "gte", "lt", "lte":
+			if n, err := strconv.ParseFloat(param, 64); err == nil && numeric {
+				schema[map[string]string{
+					"gt": "exclusiveMinimum", "gte": "minimum", "lt": "exclusiveMaximum", "lte": "maximum",
+				}[name]] = n
+			}
+		}
+	}
+}
+
+// applyLength translates min, max and len, which bound the value of numbers and the length of other values.
+func applyLength(schema map[string]any, t reflect.Type, rule, param string, numeric bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	var bounds [2]string
+
+	switch {
+	case numeric:
+		bounds = [2]string{"minimum", "maximum"}
+	case t.Kind() == reflect.String || t == secretType:
+		bounds = [2]string{"minLength", "maxLength"}
+	case t.Kind() == reflect.Slice:
+		bounds = [2]string{"minItems", "maxItems"}
+	case t.Kind() == reflect.Map:
+		bounds = [2]string{"minProperties", "maxProperties"}
+	default:
+		return
+	}
+
+	if rule != "max" {
+		schema[bounds[0]] = n
+	}
+
+	if rule != "min" {
+		schema[bounds[1]] = n
+	}
+}
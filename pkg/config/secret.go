@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// redacted replaces the value of a set secret wherever it is printed, logged or marshaled.
+const redacted = "[REDACTED]"
+
+// Secret is a configuration value that must not leak, such as a key or a token. Its value is only available with
+// Value: it is redacted when printed, logged or marshaled.
+//
+// A secret is either set literally or, preferably, by reference, e.g. file:///run/secrets/jwt or env://JWT_KEY.
+// References are resolved by the Resolver of their scheme when the configuration is loaded.
+type Secret struct {
+	value string
+}
+
+// NewSecret wraps a value in a Secret.
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// Value returns the secret value.
+func (s Secret) Value() string {
+	return s.value
+}
+
+// IsZero reports whether the secret is not set.
+func (s Secret) IsZero() bool {
+	return s.value == ""
+}
+
+// String returns a placeholder, or an empty string when the secret is not set.
+func (s Secret) String() string {
+	if s.value == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// GoString redacts the secret in %#v.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+// MarshalText redacts the secret in encodings relying on encoding.TextMarshaler, such as TOML.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// MarshalJSON redacts the secret in JSON, and in zap fields logged with zap.Any.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// MarshalYAML redacts the secret in YAML.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// LogValue redacts the secret in slog records.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// Resolver resolves the secret references of a scheme.
+type Resolver interface {
+	// Resolve returns the value of a reference, without its scheme: /run/secrets/jwt for file:///run/secrets/jwt.
+	Resolve(ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(ref string) (string, error)
+
+// Resolve calls f(ref).
+func (f ResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// ErrUnknownScheme is returned for a secret reference without a resolver for its scheme.
+var ErrUnknownScheme = errors.New("no resolver for the secret scheme")
+
+// FileResolver reads secrets from files, such as Docker and Kubernetes secrets. A single trailing newline is
+// removed.
+var FileResolver = ResolverFunc(func(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	value := strings.TrimSuffix(string(data), "\n")
+
+	return strings.TrimSuffix(value, "\r"), nil
+})
+
+// EnvResolver reads secrets from other environment variables. A missing variable is an error.
+var EnvResolver = ResolverFunc(func(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+})
+
+// defaultResolvers returns the resolvers available without WithResolver.
+func defaultResolvers() map[string]Resolver {
+	return map[string]Resolver{
+		"file": FileResolver,
+		"env":  EnvResolver,
+	}
+}
+
+// WithResolver resolves the secret references of a scheme, e.g. vault for vault://app/jwt, replacing the built-in
+// file and env resolvers for their scheme.
+func WithResolver(scheme string, r Resolver) Option {
+	return func(o *Options) {
+		if o.Resolvers == nil {
+			o.Resolvers = defaultResolvers()
+		} else {
+			o.Resolvers = maps.Clone(o.Resolvers)
+		}
+
+		o.Resolvers[scheme] = r
+	}
+}
+
+// referencePattern matches a secret reference, capturing its scheme and the reference itself.
+var referencePattern = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*)://(.*)$`)
+
+// resolveSecret resolves a secret reference with the resolver of its scheme. Other values are literal secrets.
+func resolveSecret(value string, resolvers map[string]Resolver) (Secret, error) {
+	m := referencePattern.FindStringSubmatch(value)
+	if m == nil {
+		return NewSecret(value), nil
+	}
+
+	r, ok := resolvers[strings.ToLower(m[1])]
+	if !ok {
+		return Secret{}, fmt.Errorf("%w: %s", ErrUnknownScheme, m[1])
+	}
+
+	resolved, err := r.Resolve(m[2])
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to resolve %s secret %s: %w", m[1], m[2], err)
+	}
+
+	return NewSecret(resolved), nil
+}
+
+var secretType = reflect.TypeOf(Secret{})
+
+// secretValue lets the validator check secrets like strings, e.g. with required or min=32.
+func secretValue(v reflect.Value) interface{} {
+	return v.Interface().(Secret).value
+}
+
+// secretHook decodes the configuration values of Secret fields, resolving references.
+func secretHook(resolvers map[string]Resolver) mapstructure.DecodeHookFuncType {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != secretType || from == secretType {
+			return data, nil
+		}
+
+		if data == nil {
+			return Secret{}, nil
+		}
+
+		return resolveSecret(fmt.Sprint(data), resolvers)
+	}
+}
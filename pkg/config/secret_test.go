@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SecretConfig defines a configuration with secrets.
+type SecretConfig struct {
+	Auth SecretAuthConfig
+}
+
+// SecretAuthConfig defines the secrets of a service.
+type SecretAuthConfig struct {
+	Issuer string `default:"greeter"`
+	Key    Secret
+	Token  Secret `validate:"required"`
+}
+
+func TestSecret_Redaction(t *testing.T) {
+	c := SecretAuthConfig{Issuer: "greeter", Token: NewSecret("s3cr3t")}
+
+	assert.Equal(t, "s3cr3t", c.Token.Value())
+	assert.False(t, c.Token.IsZero())
+	assert.True(t, c.Key.IsZero())
+
+	for _, s := range []string{fmt.Sprint(c), fmt.Sprintf("%+v", c), fmt.Sprintf("%#v", c), fmt.Sprintf("%s", c.Token)} {
+		assert.NotContains(t, s, "s3cr3t")
+	}
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Issuer":"greeter","Key":"","Token":"[REDACTED]"}`, string(data))
+
+	data, err = yaml.Marshal(c)
+	require.NoError(t, err)
+	assert.Equal(t, "issuer: greeter\nkey: \"\"\ntoken: '[REDACTED]'\n", string(data))
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Info("config", zap.Any("auth", c), zap.Stringer("token", c.Token))
+
+	entry := logs.All()[0]
+	assert.Equal(t, redacted, entry.ContextMap()["token"])
+	assert.NotContains(t, fmt.Sprint(entry.ContextMap()), "s3cr3t")
+}
+
+func TestLoadConfig_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := writeFile(t, dir, "key", "file-key\n")
+
+	t.Setenv("AUTH_KEY", "file://"+keyFile)
+	t.Setenv("AUTH_TOKEN", "env://OTHER_TOKEN")
+	t.Setenv("OTHER_TOKEN", "env-token")
+
+	cfg, err := LoadConfig(&SecretConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "file-key", cfg.Auth.Key.Value())
+	assert.Equal(t, "env-token", cfg.Auth.Token.Value())
+
+	// Literal values are secrets too
+	t.Setenv("AUTH_TOKEN", "literal-token")
+
+	cfg, err = LoadConfig(&SecretConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "literal-token", cfg.Auth.Token.Value())
+
+	// Secrets are set by files and flags like other values
+	file := writeFile(t, dir, "config.yaml", "auth:\n  token: file-token\n")
+	t.Setenv("AUTH_TOKEN", "")
+
+	cfg, err = LoadConfig(&SecretConfig{}, WithFile(file), WithArgs([]string{"--auth.key=env://OTHER_TOKEN"}))
+	require.NoError(t, err)
+	assert.Equal(t, "file-token", cfg.Auth.Token.Value())
+	assert.Equal(t, "env-token", cfg.Auth.Key.Value())
+}
+
+func TestLoadConfig_SecretResolvers(t *testing.T) {
+	vault := map[string]string{"app/token": "vault-token"}
+	stub := ResolverFunc(func(ref string) (string, error) {
+		value, ok := vault[ref]
+		if !ok {
+			return "", fmt.Errorf("%s not found", ref)
+		}
+
+		return value, nil
+	})
+
+	t.Setenv("AUTH_TOKEN", "vault://app/token")
+
+	_, err := LoadConfig(&SecretConfig{})
+	assert.ErrorIs(t, err, ErrUnknownScheme)
+
+	cfg, err := LoadConfig(&SecretConfig{}, WithResolver("vault", stub))
+	require.NoError(t, err)
+	assert.Equal(t, "vault-token", cfg.Auth.Token.Value())
+
+	// Built-in resolvers stay available, and can be replaced
+	t.Setenv("AUTH_KEY", "env://MISSING_SECRET_VAR")
+
+	_, err = LoadConfig(&SecretConfig{}, WithResolver("vault", stub))
+	assert.ErrorContains(t, err, "MISSING_SECRET_VAR is not set")
+
+	cfg, err = LoadConfig(&SecretConfig{}, WithResolver("vault", stub), WithResolver("env", ResolverFunc(
+		func(ref string) (string, error) { return strings.ToLower(ref), nil },
+	)))
+	require.NoError(t, err)
+	assert.Equal(t, "missing_secret_var", cfg.Auth.Key.Value())
+}
+
+func TestLoadConfig_SecretErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+		err   string
+	}{
+		{name: "missing file", token: "file://" + filepath.Join(t.TempDir(), "missing"), err: "failed to resolve file secret"},
+		{name: "missing variable", token: "env://MISSING_SECRET_VAR", err: "MISSING_SECRET_VAR is not set"},
+		{name: "unknown scheme", token: "vault://app/token", err: "no resolver for the secret scheme: vault"},
+		{name: "empty", token: "", err: `auth.token (AUTH_TOKEN): violates "required"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AUTH_TOKEN", tt.token)
+
+			_, err := LoadConfig(&SecretConfig{})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Layer is a source of configuration values. Later layers take precedence.
+type Layer int
+
+const (
+	// LayerDefault is the `default` struct tag.
+	LayerDefault Layer = iota
+	// LayerFile is the configuration file.
+	LayerFile
+	// LayerProfile is the file of the selected profile.
+	LayerProfile
+	// LayerEnv is an environment variable.
+	LayerEnv
+	// LayerFlag is a command line flag.
+	LayerFlag
+)
+
+// String returns the name of the layer.
+func (l Layer) String() string {
+	switch l {
+	case LayerFile:
+		return "file"
+	case LayerProfile:
+		return "profile"
+	case LayerEnv:
+		return "env"
+	case LayerFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// Origin is where a configuration value came from.
+type Origin struct {
+	Layer Layer
+	// Name is the file, environment variable or flag the value was read from. It is empty for defaults.
+	Name string
+}
+
+// String describes the origin, e.g. "env SERVER_PORT".
+func (o Origin) String() string {
+	if o.Name == "" {
+		return o.Layer.String()
+	}
+
+	return o.Layer.String() + " " + o.Name
+}
+
+// Sources maps configuration keys, e.g. "server.port", to the origin of their value.
+type Sources map[string]Origin
+
+// recordSources records the origin of every key, checking the layers from the highest precedence down. A map is
+// recorded as a whole, with the origin of its highest layer.
+func recordSources(
+	sources Sources,
+	keys []string,
+	maps []configKey,
+	files []fileLayer,
+	flags *pflag.FlagSet,
+	env func(string) string,
+) {
+	for _, key := range keys {
+		sources[key] = origin(key, files, flags, env(key))
+	}
+
+	for _, m := range maps {
+		sources[m.name] = mapOrigin(m.name, files, env(m.name))
+	}
+}
+
+func mapOrigin(key string, files []fileLayer, envName string) Origin {
+	if value, ok := os.LookupEnv(envName); ok && value != "" {
+		return Origin{Layer: LayerEnv, Name: envName}
+	}
+
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, envName+mapKeySeparator) {
+			return Origin{Layer: LayerEnv, Name: name}
+		}
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		if _, ok := lookupKey(files[i].raw, key); ok {
+			return Origin{Layer: files[i].layer, Name: files[i].path}
+		}
+	}
+
+	return Origin{Layer: LayerDefault}
+}
+
+func origin(key string, files []fileLayer, flags *pflag.FlagSet, envName string) Origin {
+	if f := flags.Lookup(key); f != nil && f.Changed {
+		return Origin{Layer: LayerFlag, Name: "--" + key}
+	}
+
+	if value, ok := os.LookupEnv(envName); ok && value != "" {
+		return Origin{Layer: LayerEnv, Name: envName}
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		if files[i].values.IsSet(key) {
+			return Origin{Layer: files[i].layer, Name: files[i].path}
+		}
+	}
+
+	return Origin{Layer: LayerDefault}
+}
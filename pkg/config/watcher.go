@@ -0,0 +1,279 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+// reloadDelay debounces the bursts of file events written by editors and deployment tools.
+const reloadDelay = 200 * time.Millisecond
+
+// Change is a configuration update, published to the subscribers of a Watcher.
+type Change[T any] struct {
+	// Old is the configuration replaced by New.
+	Old T
+	New T
+	// Keys are the changed configuration keys, e.g. "server.port". Structs are compared field by field, other
+	// values, such as maps and slices, as a whole.
+	Keys []string
+}
+
+// Changed reports whether any of the keys, or a key below one of them, changed: "greeter" matches a change of
+// "greeter.stream.maxbytes".
+func (c Change[T]) Changed(keys ...string) bool {
+	for _, changed := range c.Keys {
+		for _, key := range keys {
+			if changed == key || strings.HasPrefix(changed, key+".") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type subscription[T any] struct {
+	keys []string
+	fn   func(Change[T])
+}
+
+// Watcher holds the current configuration and reloads it when its files change or the process receives SIGHUP.
+// Reloaded configurations are validated like the first one: an invalid configuration is rejected and the last
+// good one kept. Valid changes are published to the subscribers. It is safe for concurrent use.
+type Watcher[T any] struct {
+	newConfig func() T
+	opts      Options
+	files     []string
+	current   atomic.Pointer[T]
+
+	// mu serializes reloads, so subscribers see the changes one at a time and in order.
+	mu            sync.Mutex
+	subscriptions []subscription[T]
+}
+
+// NewWatcher loads the configuration like LoadConfig. newConfig returns the empty configuration filled by each
+// load, e.g. func() *Config { return &Config{} }. Options.Sources is only filled by this first load.
+func NewWatcher[T any](newConfig func() T, opts ...Option) (*Watcher[T], error) {
+	o := newOptions(opts)
+
+	c, files, err := load(newConfig(), o)
+	if err != nil {
+		return nil, err
+	}
+
+	o.Sources = nil
+
+	w := &Watcher[T]{newConfig: newConfig, opts: o, files: files}
+	w.current.Store(&c)
+
+	return w, nil
+}
+
+// Current returns the current configuration. It must not be modified.
+func (w *Watcher[T]) Current() T {
+	return *w.current.Load()
+}
+
+// Subscribe calls fn with every change of the given keys, or with every change when no key is given. fn is
+// called synchronously by the reload, so it must not block; subscribers are called in the order they
+// subscribed.
+func (w *Watcher[T]) Subscribe(fn func(Change[T]), keys ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscriptions = append(w.subscriptions, subscription[T]{keys: keys, fn: fn})
+}
+
+// Reload loads the configuration again and publishes the change to the subscribers. The current configuration
+// is kept when the new one fails to load or validate. The returned change has no keys when nothing changed.
+func (w *Watcher[T]) Reload() (Change[T], error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	c, _, err := load(w.newConfig(), w.opts)
+	if err != nil {
+		return Change[T]{}, fmt.Errorf("failed to reload config: %w", err)
+	}
+
+	change := Change[T]{Old: w.Current(), New: c}
+	change.Keys = diff(reflect.ValueOf(change.Old), reflect.ValueOf(change.New), "", nil)
+
+	if len(change.Keys) == 0 {
+		return change, nil
+	}
+
+	w.current.Store(&c)
+
+	for _, s := range w.subscriptions {
+		if len(s.keys) == 0 || change.Changed(s.keys...) {
+			s.fn(change)
+		}
+	}
+
+	return change, nil
+}
+
+// Watch reloads the configuration whenever a file in the directory of the configuration file changes, and
+// whenever the process receives SIGHUP, until ctx is done. Rejected configurations are logged and the current
+// one is kept, so a broken edit never takes the service down.
+func (w *Watcher[T]) Watch(ctx context.Context, l logger.Logger) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Directories are watched rather than files, as editors and Kubernetes replace files instead of writing them
+	for _, dir := range w.dirs() {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-hup:
+			w.reload(l, "signal")
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if w.watches(event.Name) && (event.Has(fsnotify.Write) || event.Has(fsnotify.Create) ||
+				event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			l.Error("Configuration file watcher failed", zap.Error(err))
+		case <-timer.C:
+			w.reload(l, "file")
+		}
+	}
+}
+
+func (w *Watcher[T]) reload(l logger.Logger, trigger string) {
+	change, err := w.Reload()
+	if err != nil {
+		l.Error("Rejected configuration, keeping the current one", zap.String("trigger", trigger), zap.Error(err))
+		return
+	}
+
+	if len(change.Keys) > 0 || trigger == "signal" {
+		l.Info("Reloaded configuration", zap.String("trigger", trigger), zap.Strings("keys", change.Keys))
+	}
+}
+
+// kubernetesDataDir is the symbolic link swapped by Kubernetes to update the files of a mounted ConfigMap.
+const kubernetesDataDir = "..data"
+
+// watches reports whether a file event is about the configuration files. Other files of their directories, such
+// as logs, are ignored.
+func (w *Watcher[T]) watches(name string) bool {
+	if filepath.Base(name) == kubernetesDataDir {
+		return true
+	}
+
+	for _, file := range w.files {
+		if filepath.Clean(file) == filepath.Clean(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dirs returns the directories of the configuration files.
+func (w *Watcher[T]) dirs() []string {
+	var dirs []string
+
+	for _, file := range w.files {
+		dir := filepath.Dir(file)
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// diff appends the keys of the values that differ between a and b to keys. Struct fields are named like the
+// configuration keys, in lower case.
+func diff(a, b reflect.Value, key string, keys []string) []string {
+	for a.Kind() == reflect.Pointer || a.Kind() == reflect.Interface {
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				keys = append(keys, key)
+			}
+
+			return keys
+		}
+
+		a, b = a.Elem(), b.Elem()
+	}
+
+	if a.Kind() != reflect.Struct || !hasExportedFields(a.Type()) {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			keys = append(keys, key)
+		}
+
+		return keys
+	}
+
+	for i := range a.NumField() {
+		field := a.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.ToLower(field.Name)
+		if key != "" {
+			name = key + "." + name
+		}
+
+		keys = diff(a.Field(i), b.Field(i), name, keys)
+	}
+
+	return keys
+}
+
+// hasExportedFields reports whether a struct has exported fields. Structs without any, such as time.Time, are
+// compared as a whole.
+func hasExportedFields(t reflect.Type) bool {
+	for i := range t.NumField() {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+
+	return false
+}
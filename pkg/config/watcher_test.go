@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+// WatchedConfig defines a configuration with nested, map and validated values, to check the published diffs.
+type WatchedConfig struct {
+	Server TestServerConfig
+	Limits WatchedLimitsConfig
+}
+
+// WatchedLimitsConfig defines limits, mirroring the reloadable parts of a service configuration.
+type WatchedLimitsConfig struct {
+	Max     int           `default:"10" validate:"gt=0"`
+	Timeout time.Duration `default:"1s"`
+	Methods map[string]int
+}
+
+func newWatchedConfig() *WatchedConfig {
+	return &WatchedConfig{}
+}
+
+func TestDiff(t *testing.T) {
+	a := &WatchedConfig{Limits: WatchedLimitsConfig{Max: 1, Methods: map[string]int{"a": 1}}}
+	b := &WatchedConfig{Limits: WatchedLimitsConfig{Max: 2, Methods: map[string]int{"a": 2}}}
+
+	assert.Empty(t, diff(reflect.ValueOf(a), reflect.ValueOf(a), "", nil))
+	assert.Equal(t, []string{"limits.max", "limits.methods"}, diff(reflect.ValueOf(a), reflect.ValueOf(b), "", nil))
+
+	b.Server.Host = "example.com"
+	assert.Equal(t, []string{"server.host", "limits.max", "limits.methods"},
+		diff(reflect.ValueOf(a), reflect.ValueOf(b), "", nil))
+}
+
+func TestChange_Changed(t *testing.T) {
+	change := Change[*WatchedConfig]{Keys: []string{"server.port", "limits.methods"}}
+
+	assert.True(t, change.Changed("server"))
+	assert.True(t, change.Changed("server.port"))
+	assert.True(t, change.Changed("server.host", "limits"))
+	assert.False(t, change.Changed("server.host"))
+	assert.False(t, change.Changed("limits.max"))
+	assert.False(t, change.Changed("serv"))
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", "limits:\n  max: 5\n")
+
+	w, err := NewWatcher(newWatchedConfig, WithFile(file))
+	require.NoError(t, err)
+	assert.Equal(t, 5, w.Current().Limits.Max)
+
+	var all, limits, server []Change[*WatchedConfig]
+
+	w.Subscribe(func(c Change[*WatchedConfig]) { all = append(all, c) })
+	w.Subscribe(func(c Change[*WatchedConfig]) { limits = append(limits, c) }, "limits")
+	w.Subscribe(func(c Change[*WatchedConfig]) { server = append(server, c) }, "server.port")
+
+	// Nothing changed
+	change, err := w.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, change.Keys)
+	assert.Empty(t, all)
+
+	writeFile(t, dir, "config.yaml", "limits:\n  max: 7\n  timeout: 2s\n")
+
+	change, err = w.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"limits.max", "limits.timeout"}, change.Keys)
+	assert.Equal(t, 5, change.Old.Limits.Max)
+	assert.Equal(t, 7, change.New.Limits.Max)
+	assert.Equal(t, 2*time.Second, w.Current().Limits.Timeout)
+
+	assert.Len(t, all, 1)
+	assert.Len(t, limits, 1)
+	assert.Empty(t, server)
+}
+
+func TestWatcher_RejectsInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", "limits:\n  max: 5\n")
+
+	w, err := NewWatcher(newWatchedConfig, WithFile(file))
+	require.NoError(t, err)
+
+	called := false
+	w.Subscribe(func(Change[*WatchedConfig]) { called = true })
+
+	for _, content := range []string{"limits:\n  max: 0\n", "server:\n  port: abc\n", "limits: [unclosed\n"} {
+		writeFile(t, dir, "config.yaml", content)
+
+		_, err = w.Reload()
+		assert.Error(t, err, content)
+		assert.Equal(t, 5, w.Current().Limits.Max, "the last good configuration is kept")
+	}
+
+	assert.False(t, called)
+
+	// A valid configuration is accepted again
+	writeFile(t, dir, "config.yaml", "limits:\n  max: 6\n")
+
+	_, err = w.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, 6, w.Current().Limits.Max)
+	assert.True(t, called)
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", "limits:\n  max: 5\n")
+
+	w, err := NewWatcher(newWatchedConfig, WithFile(file), WithProfile("prod"))
+	require.NoError(t, err)
+
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+
+	w.Subscribe(func(c Change[*WatchedConfig]) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		keys = append(keys, c.Keys...)
+	}, "limits")
+
+	log, err := logger.NewZapLogger("config-test", false)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- w.Watch(ctx, log) }()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	// The watcher starts asynchronously, so keep writing until the change is seen
+	require.Eventually(t, func() bool {
+		// The profile file did not exist at startup
+		writeFile(t, dir, "config.prod.yaml", "limits:\n  max: 9\n")
+		return w.Current().Limits.Max == 9
+	}, 5*time.Second, 2*reloadDelay)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, "limits.max", keys[0])
+}
+
+func TestWatcher_Watches(t *testing.T) {
+	w, err := NewWatcher(newWatchedConfig, WithFile(writeFile(t, t.TempDir(), "config.yaml", "")), WithProfile("prod"))
+	require.NoError(t, err)
+
+	dir := filepath.Dir(w.files[0])
+
+	assert.True(t, w.watches(filepath.Join(dir, "config.yaml")))
+	assert.True(t, w.watches(filepath.Join(dir, "config.prod.yaml")))
+	assert.True(t, w.watches(filepath.Join(dir, "..data")))
+	assert.False(t, w.watches(filepath.Join(dir, "config.dev.yaml")))
+	assert.False(t, w.watches(filepath.Join(dir, "server.log")))
+}
@@ -0,0 +1,283 @@
+// Package flags evaluates feature flags per request, to roll out changes gradually.
+//
+// A flag is a boolean, a percentage or a variant flag. Boolean flags are on or off for every request, percentage
+// flags are on for a share of the callers, and variant flags pick one of several weighted variants. Flags are
+// evaluated against the Attributes of the request carried by the context: its method, its principal and its
+// request ID. Callers are bucketed by a hash of the flag name and of their principal, or of the request ID for
+// anonymous callers, so that a caller keeps the same value while the flag is unchanged.
+package flags
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+	"sort"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+// buckets is the number of buckets callers are hashed into, giving percentages a resolution of 0.01%.
+const buckets = 10000
+
+// Kind is the kind of a flag.
+type Kind string
+
+const (
+	// Bool flags are on or off for every request in scope.
+	Bool Kind = "bool"
+	// Percentage flags are on for a share of the callers.
+	Percentage Kind = "percentage"
+	// Variant flags pick one of several weighted variants.
+	Variant Kind = "variant"
+)
+
+// Reason explains the value of an evaluated flag.
+type Reason string
+
+const (
+	// ReasonUnknown is given for flags that are not defined. They are off.
+	ReasonUnknown Reason = "unknown"
+	// ReasonDisabled is given for flags that are not enabled. They are off.
+	ReasonDisabled Reason = "disabled"
+	// ReasonMethod is given for requests to a method the flag does not apply to. The flag is off.
+	ReasonMethod Reason = "method"
+	// ReasonPrincipal is given for requests of a principal listed by the flag. The flag is on.
+	ReasonPrincipal Reason = "principal"
+	// ReasonRollout is given for percentage and variant flags, on or off depending on the bucket of the caller.
+	ReasonRollout Reason = "rollout"
+	// ReasonOn is given for enabled boolean flags.
+	ReasonOn Reason = "on"
+)
+
+// WeightedVariant is a variant of a variant flag. A variant is picked for a share of the callers proportional to
+// its weight.
+type WeightedVariant struct {
+	Name   string
+	Weight int
+}
+
+// Flag defines a feature flag.
+type Flag struct {
+	// Name identifies the flag, e.g. "greeter.emoji".
+	Name string
+	Kind Kind
+	// Enabled turns the flag on. A flag that is not enabled is off for every request, whatever its other settings.
+	Enabled bool
+	// Percentage is the share of the callers, from 0 to 100, for which a percentage flag is on.
+	Percentage float64
+	// Variants are the variants of a variant flag.
+	Variants []WeightedVariant
+	// Methods restricts the flag to the listed full method names. The flag applies to every method when empty.
+	Methods []string
+	// Principals always get the flag, and the first variant of a variant flag.
+	Principals []string
+}
+
+// Attributes describe the request a flag is evaluated for.
+type Attributes struct {
+	// Method is the full method name, e.g. /greeter.Greeter/SayHello.
+	Method string
+	// Principal is the verified identity of the caller, empty for anonymous callers.
+	Principal string
+	// RequestID identifies the request, bucketing anonymous callers.
+	RequestID string
+}
+
+type attributesKey struct{}
+
+// ContextWithAttributes returns a context carrying the attributes of a request.
+func ContextWithAttributes(ctx context.Context, a Attributes) context.Context {
+	return context.WithValue(ctx, attributesKey{}, a)
+}
+
+// AttributesFromContext returns the attributes of the request carried by the context.
+func AttributesFromContext(ctx context.Context) (Attributes, bool) {
+	a, ok := ctx.Value(attributesKey{}).(Attributes)
+
+	return a, ok
+}
+
+// Evaluation is the value of a flag for a request.
+type Evaluation struct {
+	Flag    string
+	On      bool
+	Variant string
+	Reason  Reason
+}
+
+// Set holds the current flags and evaluates them. Flags are replaced with Update, e.g. when the configuration is
+// reloaded, while requests evaluate them.
+type Set struct {
+	flags  atomic.Pointer[map[string]Flag]
+	logger logger.Logger
+}
+
+// Option configures a Set.
+type Option func(*Set)
+
+// WithLogger logs every evaluation at debug level, and every update at info level.
+func WithLogger(l logger.Logger) Option {
+	return func(s *Set) {
+		s.logger = l
+	}
+}
+
+// NewSet creates a Set holding the given flags.
+func NewSet(flags []Flag, opts ...Option) *Set {
+	s := &Set{}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.store(flags)
+
+	return s
+}
+
+// Update replaces the flags.
+func (s *Set) Update(flags []Flag) {
+	s.store(flags)
+
+	if s.logger != nil {
+		names := make([]string, len(flags))
+		for i, f := range flags {
+			names[i] = f.Name
+		}
+
+		sort.Strings(names)
+		s.logger.Info("Updated feature flags", zap.Strings("flags", names))
+	}
+}
+
+func (s *Set) store(flags []Flag) {
+	m := make(map[string]Flag, len(flags))
+	for _, f := range flags {
+		m[f.Name] = f
+	}
+
+	s.flags.Store(&m)
+}
+
+// Flags returns the current flags, sorted by name.
+func (s *Set) Flags() []Flag {
+	m := *s.flags.Load()
+
+	flags := make([]Flag, 0, len(m))
+	for _, f := range m {
+		flags = append(flags, f)
+	}
+
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+	return flags
+}
+
+// Enabled reports whether a flag is on for the request of the context.
+func (s *Set) Enabled(ctx context.Context, name string) bool {
+	return s.Evaluate(ctx, name).On
+}
+
+// VariantOf returns the variant of a flag for the request of the context, or fallback when the flag is off.
+func (s *Set) VariantOf(ctx context.Context, name, fallback string) string {
+	e := s.Evaluate(ctx, name)
+	if !e.On || e.Variant == "" {
+		return fallback
+	}
+
+	return e.Variant
+}
+
+// Evaluate evaluates a flag for the request of the context. A nil Set has no flags.
+func (s *Set) Evaluate(ctx context.Context, name string) Evaluation {
+	a, _ := AttributesFromContext(ctx)
+
+	var e Evaluation
+
+	if s == nil {
+		e = Evaluation{Flag: name, Reason: ReasonUnknown}
+	} else if f, ok := (*s.flags.Load())[name]; ok {
+		e = f.Evaluate(a)
+	} else {
+		e = Evaluation{Flag: name, Reason: ReasonUnknown}
+	}
+
+	if s != nil && s.logger != nil {
+		s.logger.WithContext(ctx).Debug("Evaluated feature flag",
+			zap.String("flag", e.Flag),
+			zap.Bool("on", e.On),
+			zap.String("variant", e.Variant),
+			zap.String("reason", string(e.Reason)),
+			zap.String("grpc.method", a.Method),
+		)
+	}
+
+	return e
+}
+
+// Evaluate evaluates the flag for a request.
+func (f Flag) Evaluate(a Attributes) Evaluation {
+	e := Evaluation{Flag: f.Name}
+
+	switch {
+	case !f.Enabled:
+		e.Reason = ReasonDisabled
+	case len(f.Methods) > 0 && !slices.Contains(f.Methods, a.Method):
+		e.Reason = ReasonMethod
+	case a.Principal != "" && slices.Contains(f.Principals, a.Principal):
+		e.On, e.Reason = true, ReasonPrincipal
+		if f.Kind == Variant && len(f.Variants) > 0 {
+			e.Variant = f.Variants[0].Name
+		}
+	case f.Kind == Percentage:
+		e.On, e.Reason = float64(f.bucket(a)) < f.Percentage*buckets/100, ReasonRollout
+	case f.Kind == Variant:
+		e.Variant, e.Reason = f.pick(f.bucket(a)), ReasonRollout
+		e.On = e.Variant != ""
+	default:
+		e.On, e.Reason = true, ReasonOn
+	}
+
+	return e
+}
+
+// bucket hashes the caller into one of the buckets. Each flag hashes callers independently, so that the callers
+// getting a flag at 10% are not the same for every flag.
+func (f Flag) bucket(a Attributes) int {
+	key := a.Principal
+	if key == "" {
+		key = a.RequestID
+	}
+
+	sum := sha256.Sum256([]byte(f.Name + "\x00" + key))
+
+	return int(binary.BigEndian.Uint64(sum[:8]) % buckets)
+}
+
+// pick returns the variant of a bucket, or an empty string when no variant has a weight.
+func (f Flag) pick(bucket int) string {
+	total := 0
+	for _, v := range f.Variants {
+		total += max(v.Weight, 0)
+	}
+
+	if total == 0 {
+		return ""
+	}
+
+	// Scale the bucket to the total weight
+	point := bucket * total / buckets
+
+	for _, v := range f.Variants {
+		if point < max(v.Weight, 0) {
+			return v.Name
+		}
+
+		point -= max(v.Weight, 0)
+	}
+
+	return ""
+}
@@ -0,0 +1,203 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+// observedLogger is a logger.Logger writing to a zap observer.
+type observedLogger struct {
+	*zap.Logger
+}
+
+func (l observedLogger) With(fields ...zap.Field) logger.Logger {
+	return observedLogger{l.Logger.With(fields...)}
+}
+
+func (l observedLogger) WithContext(context.Context) logger.Logger {
+	return l
+}
+
+func (l observedLogger) Flush() error {
+	return nil
+}
+
+func request(method, principal, requestID string) context.Context {
+	return ContextWithAttributes(context.Background(),
+		Attributes{Method: method, Principal: principal, RequestID: requestID})
+}
+
+func TestFlag_Evaluate(t *testing.T) {
+	tests := []struct {
+		name  string
+		flag  Flag
+		attrs Attributes
+		want  Evaluation
+	}{
+		{
+			name: "bool on",
+			flag: Flag{Name: "f", Kind: Bool, Enabled: true},
+			want: Evaluation{Flag: "f", On: true, Reason: ReasonOn},
+		},
+		{
+			name:  "disabled",
+			flag:  Flag{Name: "f", Kind: Bool, Principals: []string{"alice"}},
+			attrs: Attributes{Principal: "alice"},
+			want:  Evaluation{Flag: "f", Reason: ReasonDisabled},
+		},
+		{
+			name:  "other method",
+			flag:  Flag{Name: "f", Kind: Bool, Enabled: true, Methods: []string{"/greeter.Greeter/SayHello"}},
+			attrs: Attributes{Method: "/greeter.Greeter/Chat"},
+			want:  Evaluation{Flag: "f", Reason: ReasonMethod},
+		},
+		{
+			name:  "targeted method",
+			flag:  Flag{Name: "f", Kind: Bool, Enabled: true, Methods: []string{"/greeter.Greeter/SayHello"}},
+			attrs: Attributes{Method: "/greeter.Greeter/SayHello"},
+			want:  Evaluation{Flag: "f", On: true, Reason: ReasonOn},
+		},
+		{
+			name:  "listed principal",
+			flag:  Flag{Name: "f", Kind: Percentage, Enabled: true, Principals: []string{"alice"}},
+			attrs: Attributes{Principal: "alice"},
+			want:  Evaluation{Flag: "f", On: true, Reason: ReasonPrincipal},
+		},
+		{
+			name: "listed principal variant",
+			flag: Flag{
+				Name: "f", Kind: Variant, Enabled: true,
+				Principals: []string{"alice"}, Variants: []WeightedVariant{{"a", 0}, {"b", 1}},
+			},
+			attrs: Attributes{Principal: "alice"},
+			want:  Evaluation{Flag: "f", On: true, Variant: "a", Reason: ReasonPrincipal},
+		},
+		{
+			name:  "percentage none",
+			flag:  Flag{Name: "f", Kind: Percentage, Enabled: true},
+			attrs: Attributes{Principal: "alice"},
+			want:  Evaluation{Flag: "f", Reason: ReasonRollout},
+		},
+		{
+			name:  "percentage all",
+			flag:  Flag{Name: "f", Kind: Percentage, Enabled: true, Percentage: 100},
+			attrs: Attributes{Principal: "alice"},
+			want:  Evaluation{Flag: "f", On: true, Reason: ReasonRollout},
+		},
+		{
+			name:  "variant without weights",
+			flag:  Flag{Name: "f", Kind: Variant, Enabled: true, Variants: []WeightedVariant{{"a", 0}}},
+			attrs: Attributes{Principal: "alice"},
+			want:  Evaluation{Flag: "f", Reason: ReasonRollout},
+		},
+		{
+			name:  "single variant",
+			flag:  Flag{Name: "f", Kind: Variant, Enabled: true, Variants: []WeightedVariant{{"a", 0}, {"b", 3}}},
+			attrs: Attributes{RequestID: "1"},
+			want:  Evaluation{Flag: "f", On: true, Variant: "b", Reason: ReasonRollout},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.flag.Evaluate(tt.attrs))
+		})
+	}
+}
+
+func TestFlag_Rollout(t *testing.T) {
+	percentage := Flag{Name: "greeter.emoji", Kind: Percentage, Enabled: true, Percentage: 25}
+	variant := Flag{Name: "greeter.style", Kind: Variant, Enabled: true, Variants: []WeightedVariant{
+		{Name: "formal", Weight: 1}, {Name: "casual", Weight: 3},
+	}}
+
+	on, variants := 0, map[string]int{}
+
+	for i := range 10000 {
+		a := Attributes{Principal: fmt.Sprintf("user-%d", i)}
+
+		e := percentage.Evaluate(a)
+		if e.On {
+			on++
+		}
+
+		// A caller keeps its value, whatever the request
+		a.RequestID = "other"
+		assert.Equal(t, e, percentage.Evaluate(a))
+
+		variants[variant.Evaluate(a).Variant]++
+	}
+
+	assert.InDelta(t, 2500, on, 200)
+	assert.InDelta(t, 2500, variants["formal"], 200)
+	assert.InDelta(t, 7500, variants["casual"], 200)
+
+	// Anonymous callers are bucketed by request
+	on = 0
+
+	for i := range 1000 {
+		if percentage.Evaluate(Attributes{RequestID: fmt.Sprint(i)}).On {
+			on++
+		}
+	}
+
+	assert.InDelta(t, 250, on, 60)
+}
+
+func TestSet(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	set := NewSet([]Flag{
+		{Name: "greeter.emoji", Kind: Bool, Enabled: true},
+		{Name: "greeter.style", Kind: Variant, Enabled: true, Variants: []WeightedVariant{{Name: "casual", Weight: 1}}},
+	}, WithLogger(observedLogger{zap.New(core)}))
+
+	ctx := request("/greeter.Greeter/SayHello", "", "req-1")
+
+	assert.True(t, set.Enabled(ctx, "greeter.emoji"))
+	assert.False(t, set.Enabled(ctx, "greeter.missing"))
+	assert.Equal(t, "casual", set.VariantOf(ctx, "greeter.style", "formal"))
+	assert.Equal(t, "formal", set.VariantOf(ctx, "greeter.missing", "formal"))
+	assert.Equal(t, []string{"greeter.emoji", "greeter.style"}, names(set.Flags()))
+
+	// Evaluations are logged with their request
+	evaluations := logs.FilterMessage("Evaluated feature flag").All()
+	require.Len(t, evaluations, 4)
+	assert.Equal(t, map[string]interface{}{
+		"flag": "greeter.emoji", "on": true, "variant": "", "reason": "on", "grpc.method": "/greeter.Greeter/SayHello",
+	}, evaluations[0].ContextMap())
+	assert.Equal(t, "unknown", evaluations[1].ContextMap()["reason"])
+
+	// Updates replace every flag
+	set.Update([]Flag{{Name: "greeter.emoji", Kind: Bool}})
+
+	assert.False(t, set.Enabled(ctx, "greeter.emoji"))
+	assert.Equal(t, "formal", set.VariantOf(ctx, "greeter.style", "formal"))
+	assert.Equal(t, []string{"greeter.emoji"}, names(set.Flags()))
+	assert.Equal(t, 1, logs.FilterMessage("Updated feature flags").Len())
+
+	// A nil set and a context without attributes evaluate every flag as off
+	var empty *Set
+
+	assert.False(t, empty.Enabled(ctx, "greeter.emoji"))
+	assert.Equal(t, Evaluation{Flag: "greeter.emoji", Reason: ReasonDisabled},
+		set.Evaluate(context.Background(), "greeter.emoji"))
+}
+
+func names(flags []Flag) []string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = f.Name
+	}
+
+	return names
+}
@@ -0,0 +1,249 @@
+// Package grpcerr provides a rich error model mapping domain errors to gRPC statuses.
+//
+// Domain errors are declared as *Error values carrying a status code, a machine readable reason and optional
+// errdetails (BadRequest, ErrorInfo, RetryInfo and LocalizedMessage). The interceptors in this package convert
+// every error returned by a handler, including plain Go errors and context errors, into a proper status so
+// clients never see codes.Unknown.
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// DefaultDomain is the ErrorInfo domain used by errors that do not set one.
+const DefaultDomain = "greeter.go-grpc"
+
+const (
+	// ReasonInternal is the reason of errors hiding an unexpected failure.
+	ReasonInternal = "INTERNAL"
+	// ReasonCanceled is the reason of errors caused by a canceled context.
+	ReasonCanceled = "CANCELED"
+	// ReasonDeadlineExceeded is the reason of errors caused by an expired context deadline.
+	ReasonDeadlineExceeded = "DEADLINE_EXCEEDED"
+)
+
+// FieldViolation describes a single invalid field of a request.
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// Error is a domain error carrying the gRPC status it maps to. Its builder methods return copies,
+// so package level error values can be safely extended.
+type Error struct {
+	code       codes.Code
+	reason     string
+	message    string
+	domain     string
+	metadata   map[string]string
+	violations []FieldViolation
+	retryDelay time.Duration
+	locale     string
+	localized  string
+	cause      error
+}
+
+// New creates an error with the given status code, machine readable reason (UPPER_SNAKE_CASE) and message.
+func New(code codes.Code, reason, message string) *Error {
+	return &Error{code: code, reason: reason, message: message}
+}
+
+// InvalidArgument creates a codes.InvalidArgument error describing the given field violations.
+func InvalidArgument(reason, message string, violations ...FieldViolation) *Error {
+	e := New(codes.InvalidArgument, reason, message)
+	e.violations = violations
+
+	return e
+}
+
+// NotFound creates a codes.NotFound error.
+func NotFound(reason, message string) *Error {
+	return New(codes.NotFound, reason, message)
+}
+
+// Unavailable creates a codes.Unavailable error advising clients to retry after the given delay.
+func Unavailable(reason, message string, retryDelay time.Duration) *Error {
+	return New(codes.Unavailable, reason, message).WithRetryDelay(retryDelay)
+}
+
+// Internal creates a codes.Internal error. The message is sent to clients and must not leak internals.
+func Internal(message string) *Error {
+	return New(codes.Internal, ReasonInternal, message)
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	c.metadata = maps.Clone(e.metadata)
+	c.violations = append([]FieldViolation(nil), e.violations...)
+
+	return &c
+}
+
+// WithDomain returns a copy of the error with the given ErrorInfo domain.
+func (e *Error) WithDomain(domain string) *Error {
+	c := e.clone()
+	c.domain = domain
+
+	return c
+}
+
+// WithMetadata returns a copy of the error with an additional ErrorInfo metadata entry.
+func (e *Error) WithMetadata(key, value string) *Error {
+	c := e.clone()
+	if c.metadata == nil {
+		c.metadata = make(map[string]string)
+	}
+
+	c.metadata[key] = value
+
+	return c
+}
+
+// WithFieldViolation returns a copy of the error with an additional BadRequest field violation.
+func (e *Error) WithFieldViolation(field, description string) *Error {
+	c := e.clone()
+	c.violations = append(c.violations, FieldViolation{Field: field, Description: description})
+
+	return c
+}
+
+// WithRetryDelay returns a copy of the error advising clients to retry after the given delay.
+func (e *Error) WithRetryDelay(d time.Duration) *Error {
+	c := e.clone()
+	c.retryDelay = d
+
+	return c
+}
+
+// WithLocalizedMessage returns a copy of the error with a message for end users in the given locale.
+func (e *Error) WithLocalizedMessage(locale, message string) *Error {
+	c := e.clone()
+	c.locale = locale
+	c.localized = message
+
+	return c
+}
+
+// Wrap returns a copy of the error caused by err. The cause is available through errors.Unwrap
+// but is never sent to clients.
+func (e *Error) Wrap(err error) *Error {
+	c := e.clone()
+	c.cause = err
+
+	return c
+}
+
+// Code returns the gRPC status code of the error.
+func (e *Error) Code() codes.Code {
+	return e.code
+}
+
+// Reason returns the machine readable reason of the error.
+func (e *Error) Reason() string {
+	return e.reason
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.code, e.message, e.cause)
+	}
+
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+// Unwrap returns the cause of the error.
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an *Error with the same code and reason, so that copies made by the
+// builder methods still match the value they were derived from.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return e.code == t.code && e.reason == t.reason
+}
+
+// GRPCStatus returns the status of the error including all its details.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.code, e.message)
+
+	details := make([]protoadapt.MessageV1, 0, 4)
+
+	if e.reason != "" {
+		domain := e.domain
+		if domain == "" {
+			domain = DefaultDomain
+		}
+
+		details = append(details, &errdetails.ErrorInfo{Reason: e.reason, Domain: domain, Metadata: e.metadata})
+	}
+
+	if len(e.violations) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, v := range e.violations {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+
+		details = append(details, br)
+	}
+
+	if e.retryDelay > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(e.retryDelay)})
+	}
+
+	if e.localized != "" {
+		details = append(details, &errdetails.LocalizedMessage{Locale: e.locale, Message: e.localized})
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+
+	return withDetails
+}
+
+// ToStatus converts any error into a status. Errors already carrying a status keep it, context errors map to
+// codes.Canceled and codes.DeadlineExceeded, and every other error becomes codes.Internal without exposing
+// its message. It returns nil for a nil error.
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.GRPCStatus()
+	}
+
+	if st, ok := status.FromError(err); ok && st.Code() != codes.Unknown {
+		return st
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return New(codes.Canceled, ReasonCanceled, "request canceled").GRPCStatus()
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(codes.DeadlineExceeded, ReasonDeadlineExceeded, "deadline exceeded").GRPCStatus()
+	default:
+		return Internal("internal server error").GRPCStatus()
+	}
+}
@@ -0,0 +1,98 @@
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errNotFound = NotFound("GREETING_NOT_FOUND", "greeting not found")
+
+func TestError_GRPCStatus(t *testing.T) {
+	err := InvalidArgument("NAME_REQUIRED", "name is required", FieldViolation{Field: "name", Description: "empty"}).
+		WithMetadata("service", "greeter").
+		WithRetryDelay(time.Second).
+		WithLocalizedMessage("en-US", "Please tell us your name.")
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "name is required", st.Message())
+
+	var (
+		info      *errdetails.ErrorInfo
+		badReq    *errdetails.BadRequest
+		retry     *errdetails.RetryInfo
+		localized *errdetails.LocalizedMessage
+	)
+
+	for _, d := range st.Details() {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = v
+		case *errdetails.BadRequest:
+			badReq = v
+		case *errdetails.RetryInfo:
+			retry = v
+		case *errdetails.LocalizedMessage:
+			localized = v
+		}
+	}
+
+	require.NotNil(t, info)
+	assert.Equal(t, "NAME_REQUIRED", info.GetReason())
+	assert.Equal(t, DefaultDomain, info.GetDomain())
+	assert.Equal(t, "greeter", info.GetMetadata()["service"])
+
+	require.NotNil(t, badReq)
+	require.Len(t, badReq.GetFieldViolations(), 1)
+	assert.Equal(t, "name", badReq.GetFieldViolations()[0].GetField())
+
+	require.NotNil(t, retry)
+	assert.Equal(t, time.Second, retry.GetRetryDelay().AsDuration())
+
+	require.NotNil(t, localized)
+	assert.Equal(t, "en-US", localized.GetLocale())
+}
+
+func TestError_BuildersDoNotMutate(t *testing.T) {
+	derived := errNotFound.WithMetadata("id", "42").Wrap(errors.New("db: no rows"))
+
+	assert.ErrorIs(t, derived, errNotFound)
+	assert.Nil(t, errNotFound.metadata)
+	assert.NoError(t, errNotFound.Unwrap())
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{name: "domain error", err: errNotFound, code: codes.NotFound, message: "greeting not found"},
+		{name: "wrapped domain error", err: fmt.Errorf("lookup: %w", errNotFound), code: codes.NotFound, message: "greeting not found"},
+		{name: "status error", err: status.Error(codes.PermissionDenied, "denied"), code: codes.PermissionDenied, message: "denied"},
+		{name: "canceled", err: fmt.Errorf("send: %w", context.Canceled), code: codes.Canceled, message: "request canceled"},
+		{name: "deadline", err: context.DeadlineExceeded, code: codes.DeadlineExceeded, message: "deadline exceeded"},
+		{name: "plain error", err: errors.New("db password is hunter2"), code: codes.Internal, message: "internal server error"},
+		{name: "unknown status", err: status.Error(codes.Unknown, "what"), code: codes.Internal, message: "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := ToStatus(tt.err)
+			assert.Equal(t, tt.code, st.Code())
+			assert.Equal(t, tt.message, st.Message())
+		})
+	}
+
+	assert.Nil(t, ToStatus(nil))
+}
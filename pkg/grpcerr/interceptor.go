@@ -0,0 +1,53 @@
+package grpcerr
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+// UnaryServerInterceptor returns a new unary server interceptor converting handler errors with ToStatus.
+// Errors converted to codes.Internal are logged with their original message, which is hidden from clients.
+func UnaryServerInterceptor(l logger.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, convert(ctx, l, info.FullMethod, err)
+		}
+
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor converting handler errors with ToStatus.
+func StreamServerInterceptor(l logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return convert(ss.Context(), l, info.FullMethod, err)
+		}
+
+		return nil
+	}
+}
+
+func convert(ctx context.Context, l logger.Logger, method string, err error) error {
+	st := ToStatus(err)
+
+	if st.Code() == codes.Internal {
+		l.WithContext(ctx).Error("request failed with internal error",
+			zap.String("grpc.method", method),
+			zap.Error(err),
+		)
+	}
+
+	return st.Err()
+}
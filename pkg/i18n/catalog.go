@@ -0,0 +1,117 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+// reloadDelay debounces the bursts of file events written by editors and deployment tools.
+const reloadDelay = 200 * time.Millisecond
+
+// Catalog holds the current Bundle and replaces it when its sources change. It is safe for concurrent use.
+type Catalog struct {
+	// mu serializes reloads, and guards load.
+	mu     sync.Mutex
+	load   func() (*Bundle, error)
+	bundle atomic.Pointer[Bundle]
+}
+
+// NewCatalog creates a Catalog from the bundle returned by load. load is called again on every reload, so it
+// must build and check a complete bundle from scratch.
+func NewCatalog(load func() (*Bundle, error)) (*Catalog, error) {
+	c := &Catalog{}
+	if err := c.Replace(load); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Bundle returns the current bundle.
+func (c *Catalog) Bundle() *Bundle {
+	return c.bundle.Load()
+}
+
+// Reload rebuilds the bundle. The current bundle is kept when loading fails.
+func (c *Catalog) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.replace(c.load)
+}
+
+// Replace rebuilds the bundle from load, which is then used by later reloads, e.g. when the configuration of
+// the sources changes. The current bundle and load function are kept when loading fails.
+func (c *Catalog) Replace(load func() (*Bundle, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.replace(load)
+}
+
+func (c *Catalog) replace(load func() (*Bundle, error)) error {
+	b, err := load()
+	if err != nil {
+		return err
+	}
+
+	c.load = load
+	c.bundle.Store(b)
+
+	return nil
+}
+
+// Watch reloads the catalog whenever a file in dir changes, until ctx is done. Failed reloads are logged and
+// keep the current bundle, so a broken edit never takes the messages down.
+func (c *Catalog) Watch(ctx context.Context, dir string, l logger.Logger) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) ||
+				event.Has(fsnotify.Rename) {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			l.Error("Message file watcher failed", zap.String("dir", dir), zap.Error(err))
+		case <-timer.C:
+			if err := c.Reload(); err != nil {
+				l.Error("Failed to reload messages, keeping the current ones", zap.String("dir", dir), zap.Error(err))
+				continue
+			}
+
+			l.Info("Reloaded messages", zap.String("dir", dir), zap.Strings("locales", c.Bundle().Locales()))
+		}
+	}
+}
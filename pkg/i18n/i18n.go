@@ -0,0 +1,273 @@
+// Package i18n provides localized message templates with plural forms and locale fallback.
+//
+// Messages are text/template templates grouped by locale in YAML files named after their BCP 47 tag,
+// e.g. "en.yaml" or "pt-BR.yaml". A message is either a single template or a map of CLDR plural forms
+// (zero, one, two, few, many, other) to templates:
+//
+//	hello: "Hello, {{.Name}}"
+//	many:
+//	  one: "Hello, {{join .Names}}!"
+//	  other: "Hello to all {{.Count}} of you, {{join .Names}}!"
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// pluralForms maps the plural form names accepted in message files to their CLDR forms.
+var pluralForms = map[string]plural.Form{
+	"zero":  plural.Zero,
+	"one":   plural.One,
+	"two":   plural.Two,
+	"few":   plural.Few,
+	"many":  plural.Many,
+	"other": plural.Other,
+}
+
+// funcs are the functions available in every template.
+var funcs = template.FuncMap{
+	"join": func(items []string) string { return strings.Join(items, ", ") },
+}
+
+// message is a parsed message with one template per plural form.
+type message struct {
+	forms map[plural.Form]*template.Template
+}
+
+// form returns the template for the count in the given locale, falling back to the other form.
+// An explicit zero form is used for a count of zero even in locales whose plural rules have none.
+func (m *message) form(tag language.Tag, count int) *template.Template {
+	if t, ok := m.forms[plural.Zero]; ok && count == 0 {
+		return t
+	}
+
+	if t, ok := m.forms[plural.Cardinal.MatchPlural(tag, count, 0, 0, 0, 0)]; ok {
+		return t
+	}
+
+	return m.forms[plural.Other]
+}
+
+// Bundle holds the messages of every locale. A Bundle is built once and then only read, so it is safe for
+// concurrent use after loading.
+type Bundle struct {
+	fallback language.Tag
+	locales  map[language.Tag]map[string]*message
+}
+
+// NewBundle creates an empty Bundle. The fallback locale is tried after every requested locale.
+func NewBundle(fallback language.Tag) *Bundle {
+	return &Bundle{fallback: fallback, locales: make(map[language.Tag]map[string]*message)}
+}
+
+// Fallback returns the locale tried after every requested locale.
+func (b *Bundle) Fallback() language.Tag {
+	return b.fallback
+}
+
+// LoadFS adds the messages of every "<locale>.yaml" or "<locale>.yml" file at the root of fsys.
+// Messages already in the bundle are replaced by the ones loaded, so later sources override earlier ones.
+func (b *Bundle) LoadFS(fsys fs.FS) error {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("failed to list message files: %w", err)
+	}
+
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		tag, err := language.Parse(strings.TrimSuffix(entry.Name(), ext))
+		if err != nil {
+			return fmt.Errorf("invalid locale in message file name %q: %w", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read message file %q: %w", entry.Name(), err)
+		}
+
+		if err := b.Add(tag, data); err != nil {
+			return fmt.Errorf("invalid message file %q: %w", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// Add parses the YAML messages of a locale and adds them to the bundle.
+func (b *Bundle) Add(tag language.Tag, data []byte) error {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	msgs := b.locales[tag]
+	if msgs == nil {
+		msgs = make(map[string]*message)
+		b.locales[tag] = msgs
+	}
+
+	for key, value := range raw {
+		m, err := parseMessage(key, value)
+		if err != nil {
+			return err
+		}
+
+		msgs[key] = m
+	}
+
+	return nil
+}
+
+func parseMessage(key string, value interface{}) (*message, error) {
+	texts := make(map[string]string)
+
+	switch v := value.(type) {
+	case string:
+		texts["other"] = v
+	case map[interface{}]interface{}:
+		for form, text := range v {
+			name, ok := form.(string)
+			if !ok {
+				return nil, fmt.Errorf("message %q: invalid plural form %v", key, form)
+			}
+
+			s, ok := text.(string)
+			if !ok {
+				return nil, fmt.Errorf("message %q: plural form %q must be a string", key, name)
+			}
+
+			texts[name] = s
+		}
+	default:
+		return nil, fmt.Errorf("message %q must be a string or a map of plural forms", key)
+	}
+
+	if _, ok := texts["other"]; !ok {
+		return nil, fmt.Errorf("message %q: the 'other' plural form is required", key)
+	}
+
+	m := &message{forms: make(map[plural.Form]*template.Template)}
+
+	for name, text := range texts {
+		form, ok := pluralForms[name]
+		if !ok {
+			return nil, fmt.Errorf("message %q: unknown plural form %q", key, name)
+		}
+
+		t, err := template.New(key + "." + name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("message %q: %w", key, err)
+		}
+
+		m.forms[form] = t
+	}
+
+	return m, nil
+}
+
+// Check verifies that the fallback locale defines every key and that every template of every locale renders
+// with the sample data, so broken templates are reported when loading instead of when serving.
+func (b *Bundle) Check(sample interface{}, keys ...string) error {
+	for _, key := range keys {
+		if _, ok := b.locales[b.fallback][key]; !ok {
+			return fmt.Errorf("message %q is missing from the fallback locale %s", key, b.fallback)
+		}
+	}
+
+	for tag, msgs := range b.locales {
+		for key, m := range msgs {
+			for _, t := range m.forms {
+				if err := t.Execute(&bytes.Buffer{}, sample); err != nil {
+					return fmt.Errorf("locale %s: message %q: %w", tag, key, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Locales returns the locales of the bundle, sorted.
+func (b *Bundle) Locales() []string {
+	locales := make([]string, 0, len(b.locales))
+	for tag := range b.locales {
+		locales = append(locales, tag.String())
+	}
+
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Render renders the message in the first locale of the fallback chain that defines it, choosing the plural
+// form for count. The chain holds every preferred locale followed by its parents, e.g. "fr-CA" then "fr",
+// and ends with the fallback locale. Render returns the locale the message was rendered in.
+func (b *Bundle) Render(prefs []language.Tag, key string, count int, data interface{}) (string, language.Tag, error) {
+	for _, tag := range b.chain(prefs) {
+		m, ok := b.locales[tag][key]
+		if !ok {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := m.form(tag, count).Execute(&buf, data); err != nil {
+			return "", tag, fmt.Errorf("failed to render message %q in %s: %w", key, tag, err)
+		}
+
+		return buf.String(), tag, nil
+	}
+
+	return "", language.Und, fmt.Errorf("message %q is not defined", key)
+}
+
+// chain returns the locales to try, in order and without duplicates.
+func (b *Bundle) chain(prefs []language.Tag) []language.Tag {
+	var chain []language.Tag
+
+	seen := make(map[language.Tag]bool)
+
+	for _, pref := range append(prefs, b.fallback) {
+		for tag := pref; tag != language.Und; tag = tag.Parent() {
+			if !seen[tag] {
+				seen[tag] = true
+				chain = append(chain, tag)
+			}
+		}
+	}
+
+	return chain
+}
+
+// Preferences returns the preferred locales of a caller: the explicit locale first, then the locales of the
+// Accept-Language values ordered by quality. Values that do not parse are ignored.
+func Preferences(locale string, acceptLanguage ...string) []language.Tag {
+	var prefs []language.Tag
+
+	if locale != "" {
+		if tag, err := language.Parse(locale); err == nil {
+			prefs = append(prefs, tag)
+		}
+	}
+
+	for _, value := range acceptLanguage {
+		if tags, _, err := language.ParseAcceptLanguage(value); err == nil {
+			prefs = append(prefs, tags...)
+		}
+	}
+
+	return prefs
+}
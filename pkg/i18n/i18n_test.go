@@ -0,0 +1,242 @@
+package i18n
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+type sample struct {
+	Name  string
+	Names []string
+	Count int
+}
+
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+
+	b := NewBundle(language.English)
+	require.NoError(t, b.LoadFS(fstest.MapFS{
+		"en.yaml": {Data: []byte(`
+hello: "Hello, {{.Name}}"
+many:
+  zero: "Hello, nobody"
+  one: "Hello, {{join .Names}}"
+  other: "Hello to all {{.Count}}"
+`)},
+		"fr.yaml":    {Data: []byte(`hello: "Bonjour, {{.Name}}"`)},
+		"pt-BR.yaml": {Data: []byte(`hello: "Olá, {{.Name}}"`)},
+		"README.md":  {Data: []byte(`ignored`)},
+	}))
+
+	return b
+}
+
+func TestBundle_Render(t *testing.T) {
+	b := testBundle(t)
+
+	tests := []struct {
+		name           string
+		prefs          []language.Tag
+		key            string
+		data           sample
+		expected       string
+		expectedLocale string
+	}{
+		{
+			name:           "fallback locale without preferences",
+			key:            "hello",
+			data:           sample{Name: "World", Count: 1},
+			expected:       "Hello, World",
+			expectedLocale: "en",
+		},
+		{
+			name:           "preferred locale",
+			prefs:          []language.Tag{language.French},
+			key:            "hello",
+			data:           sample{Name: "World", Count: 1},
+			expected:       "Bonjour, World",
+			expectedLocale: "fr",
+		},
+		{
+			name:           "regional locale falls back to its language",
+			prefs:          []language.Tag{language.MustParse("fr-CA")},
+			key:            "hello",
+			data:           sample{Name: "World", Count: 1},
+			expected:       "Bonjour, World",
+			expectedLocale: "fr",
+		},
+		{
+			name:           "unsupported locale falls back to the next preference",
+			prefs:          []language.Tag{language.German, language.MustParse("pt-BR")},
+			key:            "hello",
+			data:           sample{Name: "World", Count: 1},
+			expected:       "Olá, World",
+			expectedLocale: "pt-BR",
+		},
+		{
+			name:           "missing key falls back to the fallback locale",
+			prefs:          []language.Tag{language.French},
+			key:            "many",
+			data:           sample{Names: []string{"Alice"}, Count: 1},
+			expected:       "Hello, Alice",
+			expectedLocale: "en",
+		},
+		{
+			name:           "plural other form",
+			key:            "many",
+			data:           sample{Names: []string{"Alice", "Bob"}, Count: 2},
+			expected:       "Hello to all 2",
+			expectedLocale: "en",
+		},
+		{
+			name:           "explicit zero form",
+			key:            "many",
+			data:           sample{Count: 0},
+			expected:       "Hello, nobody",
+			expectedLocale: "en",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, tag, err := b.Render(tt.prefs, tt.key, tt.data.Count, tt.data)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, msg)
+			assert.Equal(t, tt.expectedLocale, tag.String())
+		})
+	}
+}
+
+func TestBundle_RenderUnknownKey(t *testing.T) {
+	_, _, err := testBundle(t).Render(nil, "unknown", 1, sample{})
+
+	assert.Error(t, err)
+}
+
+func TestBundle_InvalidMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+	}{
+		{name: "invalid locale", file: "not_a_locale!.yaml", data: `hello: "Hello"`},
+		{name: "invalid yaml", file: "en.yaml", data: `hello: [`},
+		{name: "invalid template", file: "en.yaml", data: `hello: "Hello, {{.Name"`},
+		{name: "missing other form", file: "en.yaml", data: "hello:\n  one: \"Hello\""},
+		{name: "unknown plural form", file: "en.yaml", data: "hello:\n  other: \"Hello\"\n  several: \"Hi\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewBundle(language.English).LoadFS(fstest.MapFS{tt.file: {Data: []byte(tt.data)}})
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBundle_Check(t *testing.T) {
+	b := testBundle(t)
+
+	assert.NoError(t, b.Check(sample{Name: "World", Names: []string{"Alice"}, Count: 1}, "hello", "many"))
+	assert.ErrorContains(t, b.Check(sample{}, "goodbye"), `message "goodbye" is missing`)
+
+	// Templates referencing fields the data does not have fail the check
+	require.NoError(t, b.Add(language.French, []byte(`hello: "Bonjour, {{.Nom}}"`)))
+	assert.ErrorContains(t, b.Check(sample{}, "hello"), "locale fr")
+}
+
+func TestPreferences(t *testing.T) {
+	prefs := Preferences("es", "fr-CA;q=0.5, de", "invalid;;")
+
+	assert.Equal(t, []language.Tag{
+		language.Spanish,
+		language.German,
+		language.MustParse("fr-CA"),
+	}, prefs)
+}
+
+func TestCatalog_Reload(t *testing.T) {
+	fail := false
+	c, err := NewCatalog(func() (*Bundle, error) {
+		if fail {
+			return nil, assert.AnError
+		}
+
+		return NewBundle(language.English), nil
+	})
+	require.NoError(t, err)
+
+	current := c.Bundle()
+	fail = true
+
+	assert.Error(t, c.Reload())
+	assert.Same(t, current, c.Bundle(), "a failed reload must keep the current bundle")
+}
+
+func TestCatalog_Replace(t *testing.T) {
+	c, err := NewCatalog(func() (*Bundle, error) { return NewBundle(language.English), nil })
+	require.NoError(t, err)
+
+	current := c.Bundle()
+
+	assert.Error(t, c.Replace(func() (*Bundle, error) { return nil, assert.AnError }))
+	assert.Same(t, current, c.Bundle(), "a failed replace must keep the current bundle")
+	require.NoError(t, c.Reload(), "a failed replace must keep the current load function")
+
+	loads := 0
+	require.NoError(t, c.Replace(func() (*Bundle, error) {
+		loads++
+		return NewBundle(language.French), nil
+	}))
+	require.NoError(t, c.Reload())
+	assert.Equal(t, 2, loads, "reloads use the new load function")
+}
+
+func TestCatalog_Watch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "en.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`hello: "Hello"`), 0o600))
+
+	c, err := NewCatalog(func() (*Bundle, error) {
+		b := NewBundle(language.English)
+		return b, b.LoadFS(os.DirFS(dir))
+	})
+	require.NoError(t, err)
+
+	l, err := logger.NewZapLogger("test", false)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watching := make(chan error, 1)
+	go func() { watching <- c.Watch(ctx, dir, l) }()
+
+	render := func() string {
+		msg, _, _ := c.Bundle().Render(nil, "hello", 1, nil)
+		return msg
+	}
+
+	// The watcher may start after a write, so write again until the change is picked up. Writes are spaced
+	// beyond the reload delay, as every event postpones the reload.
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, os.WriteFile(file, []byte(`hello: "Hi"`), 0o600))
+		time.Sleep(2 * reloadDelay)
+		return render() == "Hi"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-watching)
+}
@@ -0,0 +1,122 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// recordsBucket holds the records keyed by idempotency key.
+var recordsBucket = []byte("idempotency")
+
+// BoltStore keeps records in an embedded BoltDB file, so that they survive restarts.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the BoltDB file at path, creating it and its directory when missing. Expired records are
+// removed when the file is opened and whenever they are read.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create idempotency store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open idempotency store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(recordsBucket)
+		if err != nil {
+			return err
+		}
+
+		return purgeExpired(b, time.Now())
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize idempotency store %s: %w", path, err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Get implements Store.
+func (s *BoltStore) Get(_ context.Context, key string) (*Record, error) {
+	var r *Record
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(recordsBucket).Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+
+		r = &Record{}
+
+		return json.Unmarshal(data, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.expired(time.Now()) {
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(recordsBucket).Delete([]byte(key))
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, ErrNotFound
+	}
+
+	return r, nil
+}
+
+// Put implements Store.
+func (s *BoltStore) Put(_ context.Context, key string, r *Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(recordsBucket).Put([]byte(key), data)
+	})
+}
+
+// Close implements Store.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// purgeExpired deletes the records of the bucket expired at t.
+func purgeExpired(b *bolt.Bucket, t time.Time) error {
+	var expired [][]byte
+
+	err := b.ForEach(func(k, v []byte) error {
+		r := &Record{}
+		if err := json.Unmarshal(v, r); err != nil || r.expired(t) {
+			expired = append(expired, k)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+// Package idempotency stores the outcome of calls made with an idempotency key, so that retried calls
+// replay the first outcome instead of running again.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// ErrNotFound is returned by Store.Get when no live record exists for the key.
+var ErrNotFound = errors.New("idempotency record not found")
+
+// Record is the outcome of a call.
+type Record struct {
+	// Fingerprint identifies the request of the call, so that a key reused for another request is detected.
+	Fingerprint string `json:"fingerprint"`
+	// Response is the response of a successful call, marshaled as a google.protobuf.Any.
+	Response []byte `json:"response,omitempty"`
+	// Status is the status of a failed call, marshaled as a google.rpc.Status.
+	Status []byte `json:"status,omitempty"`
+	// ExpiresAt is the time after which the record is discarded.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Store keeps records by key. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the record of the key, or ErrNotFound when it is missing or expired.
+	Get(ctx context.Context, key string) (*Record, error)
+	// Put stores the record of the key, replacing any previous record.
+	Put(ctx context.Context, key string, r *Record) error
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// Fingerprint returns the fingerprint of a request: the hash of its deterministic wire encoding.
+func Fingerprint(req proto.Message) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NewRecord creates the record of a call that returned resp and err, expiring after ttl.
+func NewRecord(fingerprint string, resp proto.Message, err error, ttl time.Duration) (*Record, error) {
+	r := &Record{Fingerprint: fingerprint, ExpiresAt: time.Now().Add(ttl)}
+
+	var marshalErr error
+
+	if err != nil {
+		r.Status, marshalErr = proto.Marshal(status.Convert(err).Proto())
+	} else {
+		var a *anypb.Any
+		if a, marshalErr = anypb.New(resp); marshalErr == nil {
+			r.Response, marshalErr = proto.Marshal(a)
+		}
+	}
+
+	if marshalErr != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", marshalErr)
+	}
+
+	return r, nil
+}
+
+// Decode returns the recorded response of a successful call, or the recorded status of a failed one. The
+// response type must be linked into the binary.
+func (r *Record) Decode() (proto.Message, *status.Status, error) {
+	if r.Status != nil {
+		s := &spb.Status{}
+		if err := proto.Unmarshal(r.Status, s); err != nil {
+			return nil, nil, fmt.Errorf("corrupt idempotency record status: %w", err)
+		}
+
+		return nil, status.FromProto(s), nil
+	}
+
+	a := &anypb.Any{}
+	if err := proto.Unmarshal(r.Response, a); err != nil {
+		return nil, nil, fmt.Errorf("corrupt idempotency record response: %w", err)
+	}
+
+	resp, err := a.UnmarshalNew()
+	if err != nil {
+		return nil, nil, fmt.Errorf("corrupt idempotency record response: %w", err)
+	}
+
+	return resp, nil, nil
+}
+
+// expired reports whether the record is expired at t.
+func (r *Record) expired(t time.Time) bool {
+	return !r.ExpiresAt.IsZero() && !t.Before(r.ExpiresAt)
+}
@@ -0,0 +1,156 @@
+package idempotency
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+func TestRecord(t *testing.T) {
+	reply := &pb.HelloReply{Message: "Hello, Alice"}
+
+	r, err := NewRecord("fp", reply, nil, time.Minute)
+	require.NoError(t, err)
+
+	resp, st, err := r.Decode()
+	require.NoError(t, err)
+	assert.Nil(t, st)
+	assert.True(t, proto.Equal(reply, resp))
+
+	r, err = NewRecord("fp", nil, status.Error(codes.NotFound, "not found"), time.Minute)
+	require.NoError(t, err)
+
+	resp, st, err = r.Decode()
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "not found", st.Message())
+}
+
+func TestFingerprint(t *testing.T) {
+	a, err := Fingerprint(&pb.HelloRequest{Name: "Alice"})
+	require.NoError(t, err)
+
+	b, err := Fingerprint(&pb.HelloRequest{Name: "Alice"})
+	require.NoError(t, err)
+
+	c, err := Fingerprint(&pb.HelloRequest{Name: "Bob"})
+	require.NoError(t, err)
+
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+}
+
+func TestStores(t *testing.T) {
+	bolt, err := OpenBoltStore(filepath.Join(t.TempDir(), "idempotency.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = bolt.Close() })
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(0),
+		"bolt":   bolt,
+	}
+
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := s.Get(ctx, "missing")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, s.Put(ctx, "live", &Record{Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)}))
+			require.NoError(t, s.Put(ctx, "expired", &Record{Fingerprint: "b", ExpiresAt: time.Now().Add(-time.Second)}))
+
+			r, err := s.Get(ctx, "live")
+			require.NoError(t, err)
+			assert.Equal(t, "a", r.Fingerprint)
+
+			_, err = s.Get(ctx, "expired")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, s.Put(ctx, "live", &Record{Fingerprint: "c", ExpiresAt: time.Now().Add(time.Hour)}))
+
+			r, err = s.Get(ctx, "live")
+			require.NoError(t, err)
+			assert.Equal(t, "c", r.Fingerprint)
+		})
+	}
+}
+
+func TestMemoryStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+	expires := time.Now().Add(time.Hour)
+
+	require.NoError(t, s.Put(ctx, "a", &Record{ExpiresAt: expires}))
+	require.NoError(t, s.Put(ctx, "b", &Record{ExpiresAt: expires}))
+
+	// Reading a makes b the least recently used
+	_, err := s.Get(ctx, "a")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put(ctx, "c", &Record{ExpiresAt: expires}))
+
+	_, err = s.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{"a", "c"} {
+		_, err := s.Get(ctx, key)
+		assert.NoError(t, err, key)
+	}
+}
+
+func TestBoltStore_Reopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "idempotency.db")
+
+	s, err := OpenBoltStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Put(ctx, "live", &Record{Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)}))
+	require.NoError(t, s.Put(ctx, "expired", &Record{Fingerprint: "b", ExpiresAt: time.Now().Add(-time.Second)}))
+	require.NoError(t, s.Close())
+
+	s, err = OpenBoltStore(path)
+	require.NoError(t, err)
+
+	defer s.Close()
+
+	r, err := s.Get(ctx, "live")
+	require.NoError(t, err)
+	assert.Equal(t, "a", r.Fingerprint)
+
+	_, err = s.Get(ctx, "expired")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryStore_SweepsExpired(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(0)
+
+	t.Cleanup(func() { _ = s.Close() })
+
+	require.NoError(t, s.Put(ctx, "live", &Record{ExpiresAt: time.Now().Add(time.Hour)}))
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, s.Put(ctx, key, &Record{ExpiresAt: time.Now().Add(time.Minute)}))
+	}
+
+	// Records never read again are removed once expired
+	s.sweep(time.Now().Add(2 * time.Minute))
+
+	assert.Len(t, s.entries, 1)
+	assert.Equal(t, 1, s.order.Len())
+
+	_, err := s.Get(ctx, "live")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,128 @@
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the interval at which a MemoryStore removes its expired records.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps records in memory. It loses them on restart, and keeps at most its capacity by evicting the
+// least recently used records. Expired records are removed when read, and every sweepInterval.
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // of *memoryEntry, most recently used first
+	entries  map[string]*list.Element
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type memoryEntry struct {
+	key    string
+	record Record
+}
+
+// NewMemoryStore creates a MemoryStore holding at most capacity records. Zero keeps every live record. Close
+// stops the sweeping of expired records.
+func NewMemoryStore(capacity int) *MemoryStore {
+	s := &MemoryStore{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		done:     make(chan struct{}),
+	}
+
+	go s.sweepEvery(sweepInterval)
+
+	return s
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	entry := e.Value.(*memoryEntry)
+	if entry.record.expired(time.Now()) {
+		s.remove(e)
+		return nil, ErrNotFound
+	}
+
+	s.order.MoveToFront(e)
+	r := entry.record
+
+	return &r, nil
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(_ context.Context, key string, r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*memoryEntry).record = *r
+		s.order.MoveToFront(e)
+
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(&memoryEntry{key: key, record: *r})
+
+	if s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Back())
+	}
+
+	return nil
+}
+
+// Close implements Store.
+func (s *MemoryStore) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return nil
+}
+
+// sweepEvery removes the expired records at every interval until the store is closed.
+func (s *MemoryStore) sweepEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.sweep(now)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// sweep removes the records expired at t.
+func (s *MemoryStore) sweep(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for e := s.order.Front(); e != nil; {
+		next := e.Next()
+
+		if e.Value.(*memoryEntry).record.expired(t) {
+			s.remove(e)
+		}
+
+		e = next
+	}
+}
+
+func (s *MemoryStore) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.entries, e.Value.(*memoryEntry).key)
+}
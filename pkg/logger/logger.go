@@ -33,8 +33,23 @@ var (
 	once    sync.Once
 	log     Logger
 	initErr error
+	// level is the level of the logger, changed at runtime with SetLevel.
+	level = zap.NewAtomicLevelAt(zap.InfoLevel)
 )
 
+// SetLevel changes the level of the logger: debug, info, warn or error. It takes effect immediately, including
+// for loggers derived with With and WithContext.
+func SetLevel(name string) error {
+	l, err := zapcore.ParseLevel(name)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+
+	level.SetLevel(l)
+
+	return nil
+}
+
 // ensureLogsDir ensures the logs directory exists and returns the path to the daily log file
 func ensureLogsDir() (string, error) {
 	// Get the current working directory
@@ -99,15 +114,17 @@ func NewZapLogger(serviceName string, logInFile bool) (Logger, error) {
 		// Set the log level based on environment variable or use InfoLevel as default
 		switch os.Getenv("LOG_LEVEL") {
 		case "debug":
-			config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
+			level.SetLevel(zap.DebugLevel)
 		case "warn":
-			config.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+			level.SetLevel(zap.WarnLevel)
 		case "error":
-			config.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
+			level.SetLevel(zap.ErrorLevel)
 		default:
-			config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+			level.SetLevel(zap.InfoLevel)
 		}
 
+		config.Level = level
+
 		var zl *zap.Logger
 		zl, initErr = config.Build(zap.AddCallerSkip(1))
 
@@ -84,3 +84,24 @@ func TestLogger_WithContext(t *testing.T) {
 		assert.Empty(t, entry.Context)
 	})
 }
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(func() { level.SetLevel(zap.InfoLevel) })
+
+	core, logs := observer.New(level)
+	logger := (&zapLogger{logger: zap.New(core)}).With(zap.String("component", "test"))
+
+	logger.Debug("hidden")
+	assert.Equal(t, 0, logs.Len())
+
+	assert.NoError(t, SetLevel("debug"))
+	logger.Debug("shown")
+	assert.Equal(t, 1, logs.Len())
+
+	assert.NoError(t, SetLevel("error"))
+	logger.Warn("hidden")
+	assert.Equal(t, 1, logs.Len())
+
+	assert.Error(t, SetLevel("verbose"))
+	assert.Equal(t, zap.ErrorLevel, level.Level(), "an invalid level keeps the current one")
+}
@@ -0,0 +1,191 @@
+package pagination
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// timestampName is the full name of google.protobuf.Timestamp, the only message type treated as a scalar.
+var timestampName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
+
+// fieldPath is a resolved dotted field path, e.g. "create_time" or "author.name".
+type fieldPath []protoreflect.FieldDescriptor
+
+// resolvePath resolves a dotted path of proto field names against the message descriptor.
+// Every segment but the last must be a singular message field.
+func resolvePath(md protoreflect.MessageDescriptor, path string) (fieldPath, error) {
+	var fp fieldPath
+
+	for i, name := range strings.Split(path, ".") {
+		if md == nil {
+			return nil, fmt.Errorf("field %q: %q is not a message", path, strings.Join(strings.Split(path, ".")[:i], "."))
+		}
+
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return nil, fmt.Errorf("unknown field %q", path)
+		}
+
+		fp = append(fp, fd)
+
+		md = nil
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && fd.Message().FullName() != timestampName {
+			md = fd.Message()
+		}
+	}
+
+	return fp, nil
+}
+
+// last returns the field the path leads to.
+func (fp fieldPath) last() protoreflect.FieldDescriptor {
+	return fp[len(fp)-1]
+}
+
+// comparable reports whether the field holds a single value that can be ordered.
+func (fp fieldPath) comparable() bool {
+	fd := fp.last()
+	if fd.IsList() || fd.IsMap() {
+		return false
+	}
+
+	return fd.Message() == nil || fd.Message().FullName() == timestampName
+}
+
+// get returns the value of the path in the message. Unset intermediate messages yield the default value.
+func (fp fieldPath) get(m protoreflect.Message) protoreflect.Value {
+	for _, fd := range fp[:len(fp)-1] {
+		m = m.Get(fd).Message()
+	}
+
+	return m.Get(fp.last())
+}
+
+// compareValues orders two values of a comparable field.
+func compareValues(fd protoreflect.FieldDescriptor, a, b protoreflect.Value) int {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return compareOrdered(boolRank(a.Bool()), boolRank(b.Bool()))
+	case protoreflect.EnumKind:
+		return compareOrdered(a.Enum(), b.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return compareOrdered(a.Int(), b.Int())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return compareOrdered(a.Uint(), b.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return compareOrdered(a.Float(), b.Float())
+	case protoreflect.StringKind:
+		return strings.Compare(a.String(), b.String())
+	case protoreflect.BytesKind:
+		return strings.Compare(string(a.Bytes()), string(b.Bytes()))
+	case protoreflect.MessageKind:
+		return compareOrdered(timestampNanos(a.Message()), timestampNanos(b.Message()))
+	default:
+		return 0
+	}
+}
+
+func compareOrdered[T int64 | uint64 | float64 | int | protoreflect.EnumNumber](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// timestampNanos returns the time of a google.protobuf.Timestamp message, with unset timestamps first.
+func timestampNanos(m protoreflect.Message) int64 {
+	if !m.IsValid() {
+		return math.MinInt64
+	}
+
+	fields := m.Descriptor().Fields()
+
+	return m.Get(fields.ByName("seconds")).Int()*int64(time.Second) + int64(m.Get(fields.ByName("nanos")).Int())
+}
+
+// parseValue parses the text representation of a value of a comparable field: numbers, true or false, enum
+// value names, and RFC 3339 times for timestamps.
+func parseValue(fd protoreflect.FieldDescriptor, text string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(text)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(text)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+
+		n, err := strconv.ParseInt(text, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown value %q of enum %s", text, fd.Enum().FullName())
+		}
+
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(text, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(text, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(text, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(text), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(text)), nil
+	case protoreflect.MessageKind:
+		if text == "" {
+			return protoreflect.ValueOfMessage((*timestamppb.Timestamp)(nil).ProtoReflect()), nil
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid time %q: must be RFC 3339", text)
+		}
+
+		return protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+	}
+}
+
+// formatValue returns the text representation of a value of a comparable field, as read by parseValue.
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return strconv.FormatInt(int64(v.Enum()), 10)
+	case protoreflect.BytesKind:
+		return string(v.Bytes())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case protoreflect.MessageKind:
+		if !v.Message().IsValid() {
+			return ""
+		}
+
+		return time.Unix(0, timestampNanos(v.Message())).UTC().Format(time.RFC3339Nano)
+	default:
+		return v.String()
+	}
+}
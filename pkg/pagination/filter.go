@@ -0,0 +1,436 @@
+package pagination
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Filter is a compiled AIP-160 filter expression. It supports:
+//
+//   - comparisons of fields with =, !=, <, <=, > and >=, e.g. `create_time >= "2025-01-01T00:00:00Z"`,
+//   - the has operator :, matching an element of a repeated field, a map key or a substring of a string field,
+//   - wildcards in string equality, e.g. `name = "Al*"`,
+//   - AND, OR, NOT and parentheses. As in AIP-160, OR binds tighter than AND, and adjacent restrictions
+//     are joined with AND.
+//
+// Fields are referenced by their proto name, with dots for nested messages.
+type Filter struct {
+	root node
+}
+
+// ParseFilter compiles the filter expression against the message descriptor. An empty expression matches
+// every message.
+func ParseFilter(md protoreflect.MessageDescriptor, expr string) (*Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return &Filter{}, nil
+	}
+
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{md: md, tokens: tokens}
+
+	root, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+
+	return &Filter{root: root}, nil
+}
+
+// Match reports whether the message passes the filter.
+func (f *Filter) Match(m proto.Message) bool {
+	return f.root == nil || f.root.eval(m.ProtoReflect())
+}
+
+type node interface {
+	eval(m protoreflect.Message) bool
+}
+
+type andNode []node
+
+func (n andNode) eval(m protoreflect.Message) bool {
+	for _, c := range n {
+		if !c.eval(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type orNode []node
+
+func (n orNode) eval(m protoreflect.Message) bool {
+	for _, c := range n {
+		if c.eval(m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type notNode struct {
+	node
+}
+
+func (n notNode) eval(m protoreflect.Message) bool {
+	return !n.node.eval(m)
+}
+
+// restriction compares a field with a value.
+type restriction struct {
+	path    fieldPath
+	op      string
+	value   protoreflect.Value
+	text    string
+	pattern *regexp.Regexp
+}
+
+func (r *restriction) eval(m protoreflect.Message) bool {
+	v := r.path.get(m)
+	fd := r.path.last()
+
+	if r.op == ":" {
+		return r.has(fd, v)
+	}
+
+	if r.pattern != nil {
+		return r.pattern.MatchString(v.String()) == (r.op == "=")
+	}
+
+	c := compareValues(fd, v, r.value)
+
+	switch r.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func (r *restriction) has(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+	switch {
+	case fd.IsMap():
+		return v.Map().Has(r.value.MapKey())
+	case fd.IsList():
+		list := v.List()
+		for i := 0; i < list.Len(); i++ {
+			if compareValues(fd, list.Get(i), r.value) == 0 {
+				return true
+			}
+		}
+
+		return false
+	case fd.Kind() == protoreflect.StringKind:
+		return strings.Contains(v.String(), r.text)
+	default:
+		return compareValues(fd, v, r.value) == 0
+	}
+}
+
+// newRestriction compiles the comparison of the field path with the text of a value.
+func newRestriction(md protoreflect.MessageDescriptor, path, op, text string) (*restriction, error) {
+	fp, err := resolvePath(md, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fd := fp.last()
+	r := &restriction{path: fp, op: op, text: text}
+
+	switch {
+	case op == ":" && fd.IsMap():
+		v, err := parseValue(fd.MapKey(), text)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", path, err)
+		}
+
+		r.value = v
+
+		return r, nil
+	case op == ":" && fd.IsList() && fd.Message() == nil:
+	case !fp.comparable():
+		return nil, fmt.Errorf("field %q cannot be compared", path)
+	case (op == "=" || op == "!=") && fd.Kind() == protoreflect.StringKind && strings.Contains(text, "*"):
+		r.pattern = wildcardPattern(text)
+		return r, nil
+	}
+
+	v, err := parseValue(fd, text)
+	if err != nil {
+		return nil, fmt.Errorf("field %q: %w", path, err)
+	}
+
+	r.value = v
+
+	return r, nil
+}
+
+// wildcardPattern compiles a string where * matches any sequence of characters.
+func wildcardPattern(text string) *regexp.Regexp {
+	parts := strings.Split(text, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// token kinds.
+const (
+	tokenText = iota
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// lex splits a filter expression into tokens.
+func lex(expr string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			i++
+		case r == '"' || r == '\'':
+			var sb strings.Builder
+
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+
+				sb.WriteRune(runes[j])
+			}
+
+			if j == len(runes) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: sb.String()})
+			i = j + 1
+		case strings.ContainsRune("=!<>:", r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != '=' && r != ':' {
+				op += "="
+			}
+
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at offset %d", i)
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, text: op})
+			i += len(op)
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()\"'=!<>:", runes[j]) {
+				j++
+			}
+
+			tokens = append(tokens, token{kind: tokenText, text: string(runes[i:j])})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+// parser is a recursive descent parser of the AIP-160 grammar:
+//
+//	expression  = sequence { "AND" sequence }
+//	sequence    = factor { factor }
+//	factor      = term { "OR" term }
+//	term        = [ "NOT" ] ( "(" expression ")" | restriction )
+//	restriction = field operator value
+type parser struct {
+	md     protoreflect.MessageDescriptor
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) keyword(word string) bool {
+	if !p.done() && p.peek().kind == tokenText && p.peek().text == word {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expression() (node, error) {
+	n, err := p.sequence()
+	if err != nil {
+		return nil, err
+	}
+
+	and := andNode{n}
+
+	for p.keyword("AND") {
+		n, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+
+		and = append(and, n)
+	}
+
+	return simplify(and), nil
+}
+
+func (p *parser) sequence() (node, error) {
+	n, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	and := andNode{n}
+
+	for !p.done() && p.peek().kind != tokenClose && !(p.peek().kind == tokenText && p.peek().text == "AND") {
+		n, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+
+		and = append(and, n)
+	}
+
+	return simplify(and), nil
+}
+
+func (p *parser) factor() (node, error) {
+	n, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	or := orNode{n}
+
+	for p.keyword("OR") {
+		n, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		or = append(or, n)
+	}
+
+	if len(or) == 1 {
+		return n, nil
+	}
+
+	return or, nil
+}
+
+func (p *parser) term() (node, error) {
+	if p.keyword("NOT") {
+		n, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+
+		return notNode{n}, nil
+	}
+
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+
+	if p.peek().kind == tokenOpen {
+		p.pos++
+
+		n, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.done() || p.peek().kind != tokenClose {
+			return nil, fmt.Errorf("missing ')'")
+		}
+
+		p.pos++
+
+		return n, nil
+	}
+
+	return p.restriction()
+}
+
+func (p *parser) restriction() (node, error) {
+	if len(p.tokens)-p.pos < 3 {
+		return nil, fmt.Errorf("incomplete restriction %q: expected a field, an operator and a value", p.rest())
+	}
+
+	field, op, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	if field.kind != tokenText || op.kind != tokenOperator || (value.kind != tokenText && value.kind != tokenString) {
+		return nil, fmt.Errorf("invalid restriction %q: expected a field, an operator and a value", p.rest())
+	}
+
+	p.pos += 3
+
+	return newRestriction(p.md, field.text, op.text, value.text)
+}
+
+// rest renders the remaining tokens for error messages.
+func (p *parser) rest() string {
+	var parts []string
+	for _, t := range p.tokens[p.pos:] {
+		parts = append(parts, t.text)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// simplify unwraps single-element conjunctions.
+func simplify(and andNode) node {
+	if len(and) == 1 {
+		return and[0]
+	}
+
+	return and
+}
@@ -0,0 +1,86 @@
+package pagination
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// ReadMask is a compiled AIP-157 read mask selecting the fields returned to the caller.
+type ReadMask struct {
+	tree maskTree
+}
+
+// maskTree holds the selected field names, with the nested selection of message fields. A nil subtree
+// selects the whole field.
+type maskTree map[protoreflect.Name]maskTree
+
+// ParseReadMask compiles the read mask against the message descriptor. An empty mask, or the "*" path,
+// selects every field.
+func ParseReadMask(md protoreflect.MessageDescriptor, mask *fieldmaskpb.FieldMask) (*ReadMask, error) {
+	rm := &ReadMask{}
+
+	for _, path := range mask.GetPaths() {
+		if path == "*" {
+			return &ReadMask{}, nil
+		}
+
+		if _, err := resolvePath(md, path); err != nil {
+			return nil, err
+		}
+
+		if rm.tree == nil {
+			rm.tree = make(maskTree)
+		}
+
+		rm.tree.add(strings.Split(path, "."))
+	}
+
+	return rm, nil
+}
+
+func (t maskTree) add(names []string) {
+	name := protoreflect.Name(names[0])
+
+	sub, seen := t[name]
+	if seen && sub == nil {
+		// The whole field is already selected
+		return
+	}
+
+	if len(names) == 1 {
+		t[name] = nil
+		return
+	}
+
+	if sub == nil {
+		sub = make(maskTree)
+		t[name] = sub
+	}
+
+	sub.add(names[1:])
+}
+
+// Apply clears the fields of the message the mask does not select.
+func (rm *ReadMask) Apply(m proto.Message) {
+	if rm.tree != nil {
+		rm.tree.apply(m.ProtoReflect())
+	}
+}
+
+func (t maskTree) apply(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := t[fd.Name()]
+
+		switch {
+		case !ok:
+			m.Clear(fd)
+		case sub != nil:
+			sub.apply(v.Message())
+		}
+
+		return true
+	})
+}
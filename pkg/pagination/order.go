@@ -0,0 +1,180 @@
+package pagination
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// orderField is a single field of an order_by clause.
+type orderField struct {
+	name string
+	path fieldPath
+	desc bool
+}
+
+// OrderBy is a compiled AIP-132 order_by clause, e.g. "create_time desc, name".
+type OrderBy struct {
+	fields []orderField
+}
+
+// ParseOrderBy compiles the order_by clause against the message descriptor. Only the allowed fields may be
+// used; every comparable field is allowed when none are given.
+func ParseOrderBy(md protoreflect.MessageDescriptor, clause string, allowed ...string) (*OrderBy, error) {
+	o := &OrderBy{}
+
+	if strings.TrimSpace(clause) == "" {
+		return o, nil
+	}
+
+	for _, part := range strings.Split(clause, ",") {
+		words := strings.Fields(part)
+		if len(words) == 0 || len(words) > 2 {
+			return nil, fmt.Errorf("invalid order %q: expected a field and an optional direction", strings.TrimSpace(part))
+		}
+
+		f := orderField{name: words[0]}
+
+		if len(words) == 2 {
+			switch words[1] {
+			case "asc":
+			case "desc":
+				f.desc = true
+			default:
+				return nil, fmt.Errorf("invalid direction %q: expected asc or desc", words[1])
+			}
+		}
+
+		if len(allowed) > 0 && !contains(allowed, f.name) {
+			return nil, fmt.Errorf("cannot order by %q: expected one of %s", f.name, strings.Join(allowed, ", "))
+		}
+
+		path, err := resolvePath(md, f.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !path.comparable() {
+			return nil, fmt.Errorf("cannot order by %q", f.name)
+		}
+
+		f.path = path
+		o.fields = append(o.fields, f)
+	}
+
+	return o, nil
+}
+
+// then appends an ascending order on the field unless the clause already orders by it.
+func (o *OrderBy) then(md protoreflect.MessageDescriptor, name string) error {
+	if o.index(name) >= 0 {
+		return nil
+	}
+
+	path, err := resolvePath(md, name)
+	if err != nil {
+		return err
+	}
+
+	o.fields = append(o.fields, orderField{name: name, path: path})
+
+	return nil
+}
+
+// index returns the position of the field in the clause, or -1 when the clause does not order by it.
+func (o *OrderBy) index(name string) int {
+	for i, f := range o.fields {
+		if f.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// String returns the canonical form of the clause.
+func (o *OrderBy) String() string {
+	parts := make([]string, 0, len(o.fields))
+	for _, f := range o.fields {
+		if f.desc {
+			parts = append(parts, f.name+" desc")
+		} else {
+			parts = append(parts, f.name)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Compare orders two messages by the clause.
+func (o *OrderBy) Compare(a, b proto.Message) int {
+	return o.compareKey(a.ProtoReflect(), o.key(b.ProtoReflect()))
+}
+
+// key returns the values of the ordered fields of the message.
+func (o *OrderBy) key(m protoreflect.Message) []protoreflect.Value {
+	key := make([]protoreflect.Value, len(o.fields))
+	for i, f := range o.fields {
+		key[i] = f.path.get(m)
+	}
+
+	return key
+}
+
+// compareKey orders a message against the key of another message.
+func (o *OrderBy) compareKey(m protoreflect.Message, key []protoreflect.Value) int {
+	for i, f := range o.fields {
+		c := compareValues(f.path.last(), f.path.get(m), key[i])
+		if f.desc {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// formatKey returns the text form of a key, as read by parseKey.
+func (o *OrderBy) formatKey(key []protoreflect.Value) []string {
+	out := make([]string, len(key))
+	for i, f := range o.fields {
+		out[i] = formatValue(f.path.last(), key[i])
+	}
+
+	return out
+}
+
+// parseKey reads a key written by formatKey.
+func (o *OrderBy) parseKey(text []string) ([]protoreflect.Value, error) {
+	if len(text) != len(o.fields) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(o.fields), len(text))
+	}
+
+	key := make([]protoreflect.Value, len(text))
+
+	for i, f := range o.fields {
+		v, err := parseValue(f.path.last(), text[i])
+		if err != nil {
+			return nil, err
+		}
+
+		key[i] = v
+	}
+
+	return key, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
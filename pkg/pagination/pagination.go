@@ -0,0 +1,355 @@
+// Package pagination implements the conventions of list RPCs: AIP-158 page_size and page_token pagination
+// with opaque signed cursors, AIP-160 filter expressions, AIP-132 order_by clauses and AIP-157 read masks.
+//
+// A list RPC request implements Request, and its handler compiles it with Paginator.Parse before selecting
+// a page of results with Page:
+//
+//	list, err := paginator.Parse(md, req)
+//	if err != nil {
+//		return nil, err
+//	}
+//
+//	items, next := pagination.Page(list, all)
+//
+// Page tokens are bound to the request they were issued for: a token sent with a different filter, order or
+// other field selecting the results is rejected.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+)
+
+const (
+	// DefaultPageSize is the default number of results of a page.
+	DefaultPageSize = 50
+	// DefaultMaxPageSize is the default maximum number of results of a page. Larger page sizes are coerced.
+	DefaultMaxPageSize = 1000
+)
+
+// ErrInvalidPageToken is returned when a page token was not issued by the paginator, or was issued for
+// another request.
+var ErrInvalidPageToken = grpcerr.InvalidArgument("INVALID_PAGE_TOKEN", "invalid page token",
+	grpcerr.FieldViolation{
+		Field:       "page_token",
+		Description: "must be a next_page_token of a previous response for the same request",
+	},
+)
+
+// pageFields are the fields of a list request that may change between the pages of a list.
+var pageFields = []protoreflect.Name{"page_size", "page_token", "read_mask"}
+
+// Request is implemented by list RPC requests.
+type Request interface {
+	proto.Message
+	GetPageSize() int32
+	GetPageToken() string
+	GetFilter() string
+	GetOrderBy() string
+	GetReadMask() *fieldmaskpb.FieldMask
+}
+
+// Paginator compiles list requests and signs their page tokens.
+type Paginator struct {
+	key          []byte
+	unique       string
+	pageSize     int
+	maxPageSize  int
+	defaultOrder string
+	orderable    []string
+}
+
+// Option configures a Paginator.
+type Option func(*Paginator)
+
+// WithPageSize sets the number of results of a page when the request sets no page size.
+func WithPageSize(n int) Option {
+	return func(p *Paginator) {
+		p.pageSize = n
+	}
+}
+
+// WithMaxPageSize sets the maximum number of results of a page. Larger page sizes are coerced to it.
+func WithMaxPageSize(n int) Option {
+	return func(p *Paginator) {
+		p.maxPageSize = n
+	}
+}
+
+// WithDefaultOrder sets the order_by clause used when the request sets none.
+func WithDefaultOrder(clause string) Option {
+	return func(p *Paginator) {
+		p.defaultOrder = clause
+	}
+}
+
+// WithOrderableFields restricts the fields of order_by clauses. By default, every comparable field is allowed.
+func WithOrderableFields(names ...string) Option {
+	return func(p *Paginator) {
+		p.orderable = append(p.orderable, names...)
+	}
+}
+
+// New creates a Paginator signing page tokens with the key. unique names a field that differs between every
+// result, e.g. "id", used to break ties so that pages never skip or repeat results. A nil key is replaced by a
+// random one, so page tokens do not survive restarts.
+func New(key []byte, unique string, opts ...Option) *Paginator {
+	p := &Paginator{
+		key:         key,
+		unique:      unique,
+		pageSize:    DefaultPageSize,
+		maxPageSize: DefaultMaxPageSize,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if len(p.key) == 0 {
+		p.key = make([]byte, sha256.Size)
+		if _, err := rand.Read(p.key); err != nil {
+			panic("failed to generate pagination key: " + err.Error())
+		}
+	}
+
+	return p
+}
+
+// List is a compiled list request.
+type List struct {
+	paginator   *Paginator
+	filter      *Filter
+	order       *OrderBy
+	mask        *ReadMask
+	size        int
+	after       []protoreflect.Value
+	afterUnique string
+	ordered     bool
+	fingerprint string
+}
+
+// cursor is the signed content of a page token.
+type cursor struct {
+	// Key holds the ordered field values of the last result of the previous page.
+	Key []string `json:"k"`
+	// Fingerprint identifies the request the token was issued for.
+	Fingerprint string `json:"f"`
+}
+
+// Parse compiles a list request for results of the message type. Invalid requests fail with
+// codes.InvalidArgument.
+func (p *Paginator) Parse(md protoreflect.MessageDescriptor, req Request) (*List, error) {
+	l := &List{paginator: p, size: int(req.GetPageSize())}
+
+	if l.size < 0 {
+		return nil, grpcerr.InvalidArgument("INVALID_PAGE_SIZE", "invalid page size",
+			grpcerr.FieldViolation{Field: "page_size", Description: "must not be negative"})
+	}
+
+	if l.size == 0 {
+		l.size = p.pageSize
+	}
+
+	if p.maxPageSize > 0 && l.size > p.maxPageSize {
+		l.size = p.maxPageSize
+	}
+
+	var err error
+
+	if l.filter, err = ParseFilter(md, req.GetFilter()); err != nil {
+		return nil, grpcerr.InvalidArgument("INVALID_FILTER", "invalid filter",
+			grpcerr.FieldViolation{Field: "filter", Description: err.Error()})
+	}
+
+	clause := req.GetOrderBy()
+	if strings.TrimSpace(clause) == "" {
+		clause = p.defaultOrder
+	}
+
+	if l.order, err = ParseOrderBy(md, clause, p.orderable...); err != nil {
+		return nil, grpcerr.InvalidArgument("INVALID_ORDER_BY", "invalid order_by",
+			grpcerr.FieldViolation{Field: "order_by", Description: err.Error()})
+	}
+
+	if err := l.order.then(md, p.unique); err != nil {
+		return nil, fmt.Errorf("invalid unique field: %w", err)
+	}
+
+	if l.mask, err = ParseReadMask(md, req.GetReadMask()); err != nil {
+		return nil, grpcerr.InvalidArgument("INVALID_READ_MASK", "invalid read_mask",
+			grpcerr.FieldViolation{Field: "read_mask", Description: err.Error()})
+	}
+
+	l.ordered = p.isDefaultOrder(md, l.order)
+	l.fingerprint = fingerprint(req, l.order)
+
+	if req.GetPageToken() != "" {
+		c, err := p.decode(req.GetPageToken())
+		if err != nil || c.Fingerprint != l.fingerprint {
+			return nil, ErrInvalidPageToken
+		}
+
+		if l.after, err = l.order.parseKey(c.Key); err != nil {
+			return nil, ErrInvalidPageToken
+		}
+
+		l.afterUnique = c.Key[l.order.index(p.unique)]
+	}
+
+	return l, nil
+}
+
+// isDefaultOrder reports whether the order is the default order of the paginator.
+func (p *Paginator) isDefaultOrder(md protoreflect.MessageDescriptor, order *OrderBy) bool {
+	def, err := ParseOrderBy(md, p.defaultOrder, p.orderable...)
+	if err != nil || def.then(md, p.unique) != nil {
+		return false
+	}
+
+	return def.String() == order.String()
+}
+
+// fingerprint identifies a list request by its order and every field selecting its results, leaving out the
+// fields that may change between pages.
+func fingerprint(req Request, order *OrderBy) string {
+	m := proto.Clone(req).ProtoReflect()
+
+	for _, name := range pageFields {
+		if fd := m.Descriptor().Fields().ByName(name); fd != nil {
+			m.Clear(fd)
+		}
+	}
+
+	// The order is canonical, and filters differing only by surrounding spaces are the same
+	if fd := m.Descriptor().Fields().ByName("order_by"); fd != nil {
+		m.Clear(fd)
+	}
+
+	if fd := m.Descriptor().Fields().ByName("filter"); fd != nil {
+		m.Set(fd, protoreflect.ValueOfString(strings.TrimSpace(m.Get(fd).String())))
+	}
+
+	fields, err := proto.MarshalOptions{Deterministic: true}.Marshal(m.Interface())
+	if err != nil {
+		panic("failed to fingerprint list request: " + err.Error())
+	}
+
+	sum := sha256.Sum256(append([]byte(order.String()+"\x00"), fields...))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// Size returns the maximum number of results of the page.
+func (l *List) Size() int {
+	return l.size
+}
+
+// DefaultOrder reports whether the results are listed in the default order of the paginator. A store keeping
+// its results in that order can then read them a page at a time, after the result of After.
+func (l *List) DefaultOrder() bool {
+	return l.ordered
+}
+
+// After returns the unique field of the last result of the previous page, in text form, or an empty string on
+// the first page.
+func (l *List) After() string {
+	return l.afterUnique
+}
+
+// Match reports whether a result passes the filter of the list request.
+func (l *List) Match(m proto.Message) bool {
+	return l.filter.Match(m)
+}
+
+// Page selects the page of the list request among the candidate results: it filters and orders them, skips
+// the results of the previous pages and returns at most the page size, with the read mask applied. The next
+// page token is empty on the last page. Results are modified in place by the read mask.
+func Page[T proto.Message](l *List, items []T) ([]T, string) {
+	var page []T
+
+	for _, item := range items {
+		if !l.filter.Match(item) {
+			continue
+		}
+
+		if l.after != nil && l.order.compareKey(item.ProtoReflect(), l.after) <= 0 {
+			continue
+		}
+
+		page = append(page, item)
+	}
+
+	slices.SortStableFunc(page, func(a, b T) int { return l.order.Compare(a, b) })
+
+	var next string
+
+	if len(page) > l.size {
+		page = page[:l.size]
+		next = l.paginator.encode(cursor{
+			Key:         l.order.formatKey(l.order.key(page[len(page)-1].ProtoReflect())),
+			Fingerprint: l.fingerprint,
+		})
+	}
+
+	for _, item := range page {
+		l.mask.Apply(item)
+	}
+
+	return page, next
+}
+
+// encode signs the cursor into an opaque page token.
+func (p *Paginator) encode(c cursor) string {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		panic("failed to encode page token: " + err.Error())
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(p.sign(payload))
+}
+
+// decode verifies the signature of a page token and returns its cursor.
+func (p *Paginator) decode(token string) (cursor, error) {
+	var c cursor
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, ErrInvalidPageToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, ErrInvalidPageToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, p.sign(payload)) {
+		return c, ErrInvalidPageToken
+	}
+
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return c, ErrInvalidPageToken
+	}
+
+	return c, nil
+}
+
+func (p *Paginator) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,308 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+var (
+	greetingDescriptor = (&pb.Greeting{}).ProtoReflect().Descriptor()
+	replyDescriptor    = (&pb.HelloReply{}).ProtoReflect().Descriptor()
+	base               = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func greetings() []*pb.Greeting {
+	names := []string{"Alice", "Bob", "Alice", "Carol", "Albert"}
+	out := make([]*pb.Greeting, len(names))
+
+	for i, name := range names {
+		out[i] = &pb.Greeting{
+			Id:         string(rune('a' + i)),
+			Name:       name,
+			Message:    "Hello, " + name,
+			Method:     []string{"SayHello", "Chat"}[i%2],
+			CreateTime: timestamppb.New(base.Add(time.Duration(i) * time.Minute)),
+		}
+	}
+
+	return out
+}
+
+func ids(items []*pb.Greeting) string {
+	var s string
+	for _, g := range items {
+		s += g.GetId()
+	}
+
+	return s
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{filter: "", expected: "abcde"},
+		{filter: `name = "Alice"`, expected: "ac"},
+		{filter: `name = Alice`, expected: "ac"},
+		{filter: `name != "Alice"`, expected: "bde"},
+		{filter: `name = "Al*"`, expected: "ace"},
+		{filter: `message : "ob"`, expected: "b"},
+		{filter: `create_time >= "2025-01-01T12:02:00Z"`, expected: "cde"},
+		{filter: `create_time < "2025-01-01T12:02:00Z" AND method = "Chat"`, expected: "b"},
+		{filter: `name = "Bob" OR name = "Carol"`, expected: "bd"},
+		{filter: `method = "SayHello" name = "Alice"`, expected: "ac"},
+		{filter: `NOT name = "Alice"`, expected: "bde"},
+		{filter: `method = "Chat" OR name = "Carol" AND name = "Bob"`, expected: "b"},
+		{filter: `(method = "Chat" OR name = "Carol") AND NOT (name = "Bob")`, expected: "d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := ParseFilter(greetingDescriptor, tt.filter)
+			require.NoError(t, err)
+
+			var matched []*pb.Greeting
+			for _, g := range greetings() {
+				if f.Match(g) {
+					matched = append(matched, g)
+				}
+			}
+
+			assert.Equal(t, tt.expected, ids(matched))
+		})
+	}
+}
+
+func TestFilter_Kinds(t *testing.T) {
+	reply := &pb.HelloReply{
+		Event:    pb.ChatEvent_CHAT_EVENT_JOIN,
+		Sequence: 3,
+		Summary:  &pb.GreetingSummary{Names: []string{"Alice", "Bob"}, Count: 2, Final: true},
+	}
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{filter: `event = CHAT_EVENT_JOIN`, expected: true},
+		{filter: `event = CHAT_EVENT_LEAVE`, expected: false},
+		{filter: `sequence > 2`, expected: true},
+		{filter: `summary.count <= 1`, expected: false},
+		{filter: `summary.final = true`, expected: true},
+		{filter: `summary.names : "Bob"`, expected: true},
+		{filter: `summary.names : "Carol"`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := ParseFilter(replyDescriptor, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, f.Match(reply))
+		})
+	}
+}
+
+func TestFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{
+		`unknown = "x"`,
+		`name = `,
+		`name "Alice"`,
+		`(name = "Alice"`,
+		`name = "Alice`,
+		`create_time > "yesterday"`,
+		`name = "Alice" )`,
+		`name ! "Alice"`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, err := ParseFilter(greetingDescriptor, filter)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := ParseFilter(replyDescriptor, `summary = "x"`)
+	assert.Error(t, err, "messages cannot be compared")
+}
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		clause   string
+		expected string
+	}{
+		{clause: "create_time desc", expected: "edcba"},
+		{clause: "name, create_time desc", expected: "ecabd"},
+		{clause: "method desc, id", expected: "acebd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.clause, func(t *testing.T) {
+			o, err := ParseOrderBy(greetingDescriptor, tt.clause)
+			require.NoError(t, err)
+
+			items := greetings()
+			for i := 1; i < len(items); i++ {
+				for j := i; j > 0 && o.Compare(items[j], items[j-1]) < 0; j-- {
+					items[j], items[j-1] = items[j-1], items[j]
+				}
+			}
+
+			assert.Equal(t, tt.expected, ids(items))
+		})
+	}
+
+	for _, clause := range []string{"unknown", "name sideways", "name desc extra", "message"} {
+		t.Run(clause, func(t *testing.T) {
+			_, err := ParseOrderBy(greetingDescriptor, clause, "name", "create_time")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReadMask(t *testing.T) {
+	rm, err := ParseReadMask(replyDescriptor, &fieldmaskpb.FieldMask{Paths: []string{"message", "summary.count"}})
+	require.NoError(t, err)
+
+	reply := &pb.HelloReply{
+		Message: "Hello",
+		Locale:  "en",
+		Summary: &pb.GreetingSummary{Names: []string{"Alice"}, Count: 1},
+	}
+	rm.Apply(reply)
+
+	assert.True(t, proto.Equal(&pb.HelloReply{
+		Message: "Hello",
+		Summary: &pb.GreetingSummary{Count: 1},
+	}, reply), reply.String())
+
+	_, err = ParseReadMask(replyDescriptor, &fieldmaskpb.FieldMask{Paths: []string{"unknown"}})
+	assert.Error(t, err)
+}
+
+func TestPage(t *testing.T) {
+	p := New([]byte("secret"), "id", WithDefaultOrder("create_time"), WithMaxPageSize(2))
+
+	list := func(req *pb.ListGreetingsRequest) []string {
+		var pages []string
+
+		for {
+			l, err := p.Parse(greetingDescriptor, req)
+			require.NoError(t, err)
+
+			page, next := Page(l, greetings())
+			pages = append(pages, ids(page))
+
+			if next == "" {
+				return pages
+			}
+
+			req.PageToken = next
+		}
+	}
+
+	assert.Equal(t, []string{"ab", "cd", "e"}, list(&pb.ListGreetingsRequest{}))
+	assert.Equal(t, []string{"ab", "cd", "e"}, list(&pb.ListGreetingsRequest{PageSize: 10}))
+	assert.Equal(t, []string{"ec", "a"},
+		list(&pb.ListGreetingsRequest{Filter: `name = "Al*"`, OrderBy: "create_time desc"}))
+	assert.Equal(t, []string{"ea", "cb", "d"}, list(&pb.ListGreetingsRequest{OrderBy: "name"}))
+
+	l, err := p.Parse(greetingDescriptor,
+		&pb.ListGreetingsRequest{ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}}})
+	require.NoError(t, err)
+
+	page, _ := Page(l, greetings())
+	assert.Empty(t, page[0].GetName())
+	assert.NotEmpty(t, page[0].GetId())
+}
+
+func TestList_Cursor(t *testing.T) {
+	p := New([]byte("secret"), "id", WithDefaultOrder("create_time"))
+
+	l, err := p.Parse(greetingDescriptor, &pb.ListGreetingsRequest{PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, l.Size())
+	assert.True(t, l.DefaultOrder())
+	assert.Empty(t, l.After())
+
+	_, token := Page(l, greetings())
+
+	// The page size and the spelling of the order may change between pages
+	l, err = p.Parse(greetingDescriptor, &pb.ListGreetingsRequest{
+		PageSize: 3, OrderBy: "create_time asc", PageToken: token,
+	})
+	require.NoError(t, err)
+	assert.True(t, l.DefaultOrder())
+	assert.Equal(t, "b", l.After())
+
+	l, err = p.Parse(greetingDescriptor, &pb.ListGreetingsRequest{OrderBy: "create_time desc"})
+	require.NoError(t, err)
+	assert.False(t, l.DefaultOrder())
+}
+
+func TestPage_InvalidRequests(t *testing.T) {
+	p := New([]byte("secret"), "id")
+
+	l, err := p.Parse(greetingDescriptor, &pb.ListGreetingsRequest{PageSize: 1})
+	require.NoError(t, err)
+
+	_, token := Page(l, greetings())
+	require.NotEmpty(t, token)
+
+	tests := []struct {
+		name   string
+		req    *pb.ListGreetingsRequest
+		reason string
+	}{
+		{name: "negative page size", req: &pb.ListGreetingsRequest{PageSize: -1}, reason: "INVALID_PAGE_SIZE"},
+		{name: "invalid filter", req: &pb.ListGreetingsRequest{Filter: "name ="}, reason: "INVALID_FILTER"},
+		{name: "invalid order", req: &pb.ListGreetingsRequest{OrderBy: "unknown"}, reason: "INVALID_ORDER_BY"},
+		{
+			name:   "invalid read mask",
+			req:    &pb.ListGreetingsRequest{ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"unknown"}}},
+			reason: "INVALID_READ_MASK",
+		},
+		{name: "tampered token", req: &pb.ListGreetingsRequest{PageToken: token + "x"}, reason: "INVALID_PAGE_TOKEN"},
+		{
+			name:   "token of another filter",
+			req:    &pb.ListGreetingsRequest{PageToken: token, Filter: `name = "Bob"`},
+			reason: "INVALID_PAGE_TOKEN",
+		},
+		{
+			name:   "token of another name",
+			req:    &pb.ListGreetingsRequest{PageToken: token, Name: "Bob"},
+			reason: "INVALID_PAGE_TOKEN",
+		},
+		{
+			name:   "token of another time range",
+			req:    &pb.ListGreetingsRequest{PageToken: token, Until: timestamppb.New(base)},
+			reason: "INVALID_PAGE_TOKEN",
+		},
+	}
+
+	// Tokens signed with another key are rejected
+	other := New([]byte("other"), "id")
+	_, err = other.Parse(greetingDescriptor, &pb.ListGreetingsRequest{PageToken: token})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Parse(greetingDescriptor, tt.req)
+			require.Error(t, err)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+			var e *grpcerr.Error
+			require.ErrorAs(t, err, &e)
+			assert.Equal(t, tt.reason, e.Reason())
+		})
+	}
+}
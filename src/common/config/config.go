@@ -1,18 +1,161 @@
 // Package config provides a configuration loading and validation functionality.
 package config
 
+import (
+	"time"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/config"
+)
+
 // Config represents the application configuration. This will contain all secrets and configs for the application.
 type Config struct {
-	Server ServerConfig `validate:"required"`
-	App    AppConfig    `validate:"required"`
+	Server      ServerConfig `validate:"required"`
+	App         AppConfig    `validate:"required"`
+	Log         LogConfig
+	RequestID   RequestIDConfig
+	Deadline    DeadlineConfig
+	Greeter     GreeterConfig
+	Debug       DebugConfig
+	Audit       AuditConfig
+	Store       StoreConfig
+	Idempotency IdempotencyConfig
+	Cache       CacheConfig
+	Flags       map[string]FlagConfig `validate:"dive" desc:"Feature flags, keyed by name, e.g. greeter.emoji"`
+	Admin       AdminConfig
 }
 
 // ServerConfig represents the server configuration.
 type ServerConfig struct {
-	Port string `default:"50051" validate:"required,numeric"`
+	Port string `default:"50051" validate:"required,numeric" desc:"Port the gRPC server listens on"`
 }
 
 // AppConfig represents the application configuration.
 type AppConfig struct {
-	LogToFile bool `default:"false"`
+	LogToFile bool `default:"false" desc:"Write logs to logs/ in addition to stdout"`
+}
+
+// LogConfig represents the application log configuration. It is reloaded live.
+type LogConfig struct {
+	Level string `default:"info" validate:"oneof=debug info warn error" desc:"Lowest level logged: debug, info, warn or error"`
+}
+
+// RequestIDConfig represents the request ID handling configuration.
+type RequestIDConfig struct {
+	Generator     string `default:"uuidv4" validate:"oneof=uuidv4 uuidv7 ulid ksuid" desc:"Format of generated request IDs: uuidv4, uuidv7, ulid or ksuid"`
+	MaxLength     int    `default:"128" validate:"gt=0" desc:"Maximum length of a request ID accepted from a caller"`
+	RejectInvalid bool   `default:"false" desc:"Fail calls with an invalid request ID instead of replacing it"`
+}
+
+// DeadlineConfig represents the call timeout configuration.
+type DeadlineConfig struct {
+	Default    time.Duration                   `default:"30s" validate:"gte=0" desc:"Timeout of unary calls sent without a deadline"`
+	Max        time.Duration                   `default:"5m" validate:"gte=0" desc:"Longest deadline a unary call may ask for; longer ones are clamped"`
+	StreamIdle time.Duration                   `default:"2m" validate:"gte=0" desc:"Cancel client streams receiving no message from the client for this long"`
+	StreamMax  time.Duration                   `default:"10m" validate:"gte=0" desc:"Longest time a stream may stay open"`
+	Methods    map[string]MethodDeadlineConfig `validate:"dive" desc:"Policy overrides of individual methods, keyed by full method name"`
+}
+
+// MethodDeadlineConfig represents the timeout policy of a single method.
+type MethodDeadlineConfig struct {
+	Default    time.Duration `validate:"gte=0" desc:"Timeout of calls to the method sent without a deadline, streams included"`
+	Max        time.Duration `validate:"gte=0" desc:"Longest deadline a client may ask for the method"`
+	StreamIdle time.Duration `validate:"gte=0" desc:"Stream idle timeout of the method"`
+	StreamMax  time.Duration `validate:"gte=0" desc:"Longest time a stream of the method may stay open"`
+}
+
+// GreeterConfig represents the Greeter service configuration.
+type GreeterConfig struct {
+	Stream          StreamLimitsConfig
+	StreamGreetings StreamGreetingsConfig
+	Chat            ChatConfig
+	Templates       TemplatesConfig
+	// MaxOrdered bounds the greetings sorted by ListGreetings calls with an order_by other than the default.
+	MaxOrdered int `default:"10000" validate:"gte=0" desc:"Greetings ListGreetings sorts at most for a custom order_by"`
+	// PageTokenKey signs the page tokens of ListGreetings and the resume tokens of StreamGreetings. When empty, a
+	// random key is used and tokens do not survive restarts.
+	PageTokenKey config.Secret `desc:"Key signing ListGreetings page tokens and StreamGreetings resume tokens; random when empty"`
+}
+
+// StreamGreetingsConfig represents the limits of StreamGreetings calls.
+type StreamGreetingsConfig struct {
+	DefaultCount int           `default:"5" validate:"gt=0" desc:"Number of greetings sent when the request sets no count"`
+	MaxCount     int           `default:"100" validate:"gtefield=DefaultCount" desc:"Maximum number of greetings a request may ask for"`
+	MaxInterval  time.Duration `default:"10s" validate:"gte=0" desc:"Maximum pause between two greetings a request may ask for"`
+}
+
+// TemplatesConfig represents the greeting templates configuration.
+type TemplatesConfig struct {
+	Dir           string `desc:"Directory of <locale>.yaml files extending the built-in greetings"`
+	DefaultLocale string `default:"en" validate:"required" desc:"Locale used when the caller prefers no supported locale"`
+	Watch         bool   `default:"true" desc:"Reload the templates when a file in the directory changes"`
+}
+
+// ChatConfig represents the Chat room configuration.
+type ChatConfig struct {
+	BufferSize int `default:"64" validate:"gt=0" desc:"Messages buffered per room member before it is evicted"`
+}
+
+// StreamLimitsConfig represents the limits of the GreetManyTimes, SummarizeGreetings and Chat client streams.
+type StreamLimitsConfig struct {
+	MaxMessages int             `default:"1000" validate:"gte=0" desc:"Maximum number of messages a client may send on one stream"`
+	MaxBytes    config.ByteSize `default:"1MiB" validate:"gte=0" desc:"Maximum total size of the messages of one client stream"`
+}
+
+// DebugConfig represents the opt-in payload logging configuration.
+type DebugConfig struct {
+	PayloadMethods  []string        `desc:"Full method names whose payloads are always logged"`
+	PayloadMaxBytes config.ByteSize `default:"4KiB" validate:"gte=0" desc:"Size after which a logged payload is truncated"`
+	RedactFields    []string        `desc:"Proto field names masked in logged payloads"`
+	Token           config.Secret   `desc:"Token allowing callers to log their payloads with x-debug-log"`
+}
+
+// AuditConfig represents the audit log configuration.
+type AuditConfig struct {
+	Sink string `default:"none" validate:"oneof=none stdout file" desc:"Where audit records are written: none, stdout or file"`
+	File string `default:"logs/audit.log" validate:"required" desc:"Path of the audit log when the sink is file"`
+}
+
+// StoreConfig represents the greeting history store configuration.
+type StoreConfig struct {
+	Driver         string `default:"memory" validate:"oneof=none memory bolt" desc:"Greeting history store: none, memory or bolt"`
+	Path           string `default:"data/greetings.db" validate:"required" desc:"BoltDB file of the bolt driver"`
+	MemoryCapacity int    `default:"10000" validate:"gte=0" desc:"Greetings kept by the memory driver before dropping the oldest"`
+}
+
+// IdempotencyConfig represents the configuration of idempotency keys.
+type IdempotencyConfig struct {
+	Driver         string        `default:"memory" validate:"oneof=none memory bolt" desc:"Store of call outcomes: none, memory or bolt"`
+	Path           string        `default:"data/idempotency.db" validate:"required" desc:"BoltDB file of the bolt driver"`
+	MemoryCapacity int           `default:"10000" validate:"gte=0" desc:"Outcomes kept by the memory driver before evicting the least recent"`
+	TTL            time.Duration `default:"24h" validate:"gt=0" desc:"How long an outcome is replayed to calls with the same key"`
+	KeyMaxLength   int           `default:"128" validate:"gt=0" desc:"Maximum length of an idempotency key"`
+}
+
+// CacheConfig represents the response cache configuration.
+type CacheConfig struct {
+	MaxBytes config.ByteSize              `default:"64MiB" validate:"gt=0" desc:"Total size of the cached responses"`
+	Methods  map[string]MethodCacheConfig `validate:"dive" desc:"Cached methods, keyed by full method name"`
+}
+
+// MethodCacheConfig represents the caching policy of a single method.
+type MethodCacheConfig struct {
+	TTL      time.Duration `validate:"gt=0" desc:"How long a response is served from the cache"`
+	Metadata []string      `desc:"Metadata keys whose values are part of the cache key"`
+}
+
+// FlagConfig represents a feature flag. It is reloaded live.
+type FlagConfig struct {
+	Kind       string         `validate:"oneof=bool percentage variant" desc:"Kind of the flag: bool, percentage or variant"`
+	Enabled    bool           `desc:"Turn the flag on; a disabled flag is off for every request"`
+	Percentage float64        `validate:"gte=0,lte=100" desc:"Share of the callers, from 0 to 100, getting a percentage flag"`
+	Variants   map[string]int `validate:"dive,gte=0,lte=10000" desc:"Weights of the variants of a variant flag, keyed by name"`
+	Methods    []string       `desc:"Full method names the flag applies to; every method when empty"`
+	Principals []string       `desc:"Principals always getting the flag, and the first variant by name of a variant flag"`
+}
+
+// AdminConfig represents the Admin service configuration.
+type AdminConfig struct {
+	// Token authorizes callers of the Admin service with the 'x-admin-token' header. The service is not
+	// registered when it is empty.
+	Token config.Secret `desc:"Token authorizing callers of the Admin service; disabled when empty"`
 }
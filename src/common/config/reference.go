@@ -0,0 +1,51 @@
+package config
+
+import (
+	"bytes"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/config"
+)
+
+//go:generate go run ../../../cmd/configref -out ../../../docs/config
+
+// Reference file names, in docs/config.
+const (
+	ReferenceMarkdown = "reference.md"
+	ReferenceJSON     = "reference.json"
+	ReferenceSchema   = "config.schema.json"
+)
+
+const referenceHeader = "# Configuration Reference\n\n" +
+	"<!-- Generated by go generate ./src/common/config from the Config struct tags. DO NOT EDIT. -->\n\n" +
+	"Every key can be set in a configuration file, with an environment variable or with a flag, e.g.\n" +
+	"`--server.port=50051`. Map entries replace `<name>` with their key, e.g.\n" +
+	"`CACHE_METHODS__/greeter.Greeter/GetGreeting__TTL=30s`. Configuration files can be checked by editors with\n" +
+	"[config.schema.json](config.schema.json).\n\n"
+
+// ReferenceFiles renders the reference of Config, keyed by file name: a Markdown table, the same entries in
+// JSON, and the JSON Schema of the configuration files.
+func ReferenceFiles() (map[string][]byte, error) {
+	entries := config.Reference(&Config{})
+
+	var md, js, schema bytes.Buffer
+
+	md.WriteString(referenceHeader)
+
+	if err := config.WriteMarkdown(&md, entries); err != nil {
+		return nil, err
+	}
+
+	if err := config.WriteJSON(&js, entries); err != nil {
+		return nil, err
+	}
+
+	if err := config.WriteJSONSchema(&schema, &Config{}, "go-grpc server configuration"); err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		ReferenceMarkdown: md.Bytes(),
+		ReferenceJSON:     js.Bytes(),
+		ReferenceSchema:   schema.Bytes(),
+	}, nil
+}
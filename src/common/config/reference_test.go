@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	config_pkg "github.com/mrityunjoydey/go-grpc/pkg/config"
+)
+
+// TestReferenceFiles fails when the checked-in reference no longer matches the Config structs.
+func TestReferenceFiles(t *testing.T) {
+	files, err := ReferenceFiles()
+	require.NoError(t, err)
+
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join("..", "..", "..", "docs", "config", name))
+		require.NoError(t, err, "run go generate ./src/common/config")
+		assert.Equal(t, string(want), string(got), "docs/config/%s is out of date, run go generate ./src/common/config", name)
+	}
+}
+
+func TestReference_Descriptions(t *testing.T) {
+	for _, e := range config_pkg.Reference(&Config{}) {
+		assert.NotEmpty(t, e.Description, "%s has no desc tag", e.Key)
+	}
+}
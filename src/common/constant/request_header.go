@@ -1,3 +1,4 @@
+// Package constant provides constants for the application.
 package constant
 
 // RequestHeader is the header key in gRPC metadata.
@@ -6,4 +7,36 @@ type RequestHeader string
 const (
 	// RequestIDHeader is the header key for the request ID in gRPC metadata.
 	RequestIDHeader RequestHeader = "X-Request-ID"
+
+	// DebugLogHeader is the header key a caller sets to "true" to request payload logging for its call.
+	DebugLogHeader RequestHeader = "X-Debug-Log"
+
+	// DebugTokenHeader is the header key carrying the token that authorizes a caller to use DebugLogHeader.
+	DebugTokenHeader RequestHeader = "X-Debug-Token"
+
+	// ChatRoomHeader is the header key selecting the room a Chat stream joins.
+	ChatRoomHeader RequestHeader = "X-Chat-Room"
+
+	// AcceptLanguageHeader is the header key listing the locales a caller prefers for greetings.
+	AcceptLanguageHeader RequestHeader = "Accept-Language"
+
+	// IdempotencyKeyHeader is the header key carrying the idempotency key of a unary call. Calls retried with the
+	// same key replay the outcome of the first call.
+	IdempotencyKeyHeader RequestHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is the response header key set to "true" when a call replayed a recorded outcome.
+	IdempotentReplayedHeader RequestHeader = "Idempotent-Replayed"
+
+	// CacheControlHeader is the header key carrying the cache directives of a call: no-cache, no-store and
+	// max-age=<seconds>.
+	CacheControlHeader RequestHeader = "Cache-Control"
+
+	// CacheStatusHeader is the response header key set to "HIT" or "MISS" on calls to cached methods.
+	CacheStatusHeader RequestHeader = "X-Cache"
+
+	// AgeHeader is the response header key holding the age in seconds of a cached response.
+	AgeHeader RequestHeader = "Age"
+
+	// AdminTokenHeader is the header key carrying the token that authorizes a caller to use the Admin service.
+	AdminTokenHeader RequestHeader = "X-Admin-Token"
 )
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/audit"
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+// anonymousPrincipal is recorded for callers without a verified identity.
+const anonymousPrincipal = "anonymous"
+
+// UnaryAuditInterceptor returns a new unary server interceptor that writes an audit record for every call.
+// Failures to write the record are reported through the application logger and never fail the call.
+func UnaryAuditInterceptor(a *audit.Logger, l logger.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		writeAuditRecord(ctx, a, l, info.FullMethod, start, err)
+
+		return resp, err
+	}
+}
+
+// StreamAuditInterceptor returns a new stream server interceptor that writes an audit record for every stream
+// once it completes.
+func StreamAuditInterceptor(a *audit.Logger, l logger.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+
+		writeAuditRecord(ss.Context(), a, l, info.FullMethod, start, err)
+
+		return err
+	}
+}
+
+func writeAuditRecord(ctx context.Context, a *audit.Logger, l logger.Logger, method string, start time.Time, err error) {
+	record := audit.Record{
+		Time:      start,
+		Method:    method,
+		Code:      status.Code(err).String(),
+		Principal: Principal(ctx),
+		Duration:  time.Since(start).Seconds(),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		record.Peer = p.Addr.String()
+	}
+
+	if id, ok := RequestIDFromContext(ctx); ok {
+		record.RequestID = id
+	}
+
+	if werr := a.Write(record); werr != nil {
+		l.WithContext(ctx).Error("failed to write audit record", zap.Error(werr))
+	}
+}
+
+// Principal returns the verified identity of the caller: the subject common name of its TLS client
+// certificate. Callers without a verified certificate are reported as "anonymous".
+func Principal(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return anonymousPrincipal
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return anonymousPrincipal
+	}
+
+	var cert *x509.Certificate
+	if chains := tlsInfo.State.VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+		cert = chains[0][0]
+	}
+
+	if cert == nil || cert.Subject.CommonName == "" {
+		return anonymousPrincipal
+	}
+
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/cache"
+	"github.com/mrityunjoydey/go-grpc/src/common/constant"
+)
+
+// defaultCacheMaxBytes is the default total size of the cached responses.
+const defaultCacheMaxBytes = 64 << 20
+
+const (
+	// cacheStatusHit marks a response served from the cache.
+	cacheStatusHit = "HIT"
+	// cacheStatusMiss marks a response computed by the handler.
+	cacheStatusMiss = "MISS"
+)
+
+// CachePolicy is the caching policy of a method.
+type CachePolicy struct {
+	// TTL is how long a response is served from the cache. Zero disables caching.
+	TTL time.Duration
+	// Metadata lists the metadata keys whose values are part of the cache key, for methods whose response
+	// depends on them, e.g. "accept-language".
+	Metadata []string
+}
+
+// CacheOption configures the cache interceptor.
+type CacheOption func(*cacheOptions)
+
+type cacheOptions struct {
+	maxBytes int
+	methods  map[string]CachePolicy
+}
+
+// WithCacheMaxBytes sets the total size of the cached responses. The least recently used responses are evicted
+// to make room for new ones.
+func WithCacheMaxBytes(n int) CacheOption {
+	return func(o *cacheOptions) {
+		o.maxBytes = n
+	}
+}
+
+// WithCachedMethod caches the responses of the given full method name, e.g. "/greeter.Greeter/GetGreeting".
+// Only methods without side effects should be cached.
+func WithCachedMethod(method string, p CachePolicy) CacheOption {
+	return func(o *cacheOptions) {
+		o.methods[method] = p
+	}
+}
+
+// cacheDirectives are the cache-control directives of a call.
+type cacheDirectives struct {
+	// noCache skips the cached response, and caches the fresh one.
+	noCache bool
+	// noStore skips storing the response.
+	noStore bool
+	// maxAge is the oldest cached response accepted, when set.
+	maxAge    time.Duration
+	hasMaxAge bool
+}
+
+// parseCacheControl reads the cache-control metadata values. Unknown and malformed directives are ignored.
+func parseCacheControl(values []string) cacheDirectives {
+	var d cacheDirectives
+
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(directive)), "=")
+
+			switch name {
+			case "no-cache":
+				d.noCache = true
+			case "no-store":
+				d.noStore = true
+			case "max-age":
+				if seconds, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil && seconds >= 0 {
+					d.maxAge = time.Duration(seconds) * time.Second
+					d.hasMaxAge = true
+				}
+			}
+		}
+	}
+
+	return d
+}
+
+// UnaryCacheInterceptor returns a new unary server interceptor that caches the successful responses of the
+// configured methods. Responses are cached by method, request, caller and the metadata values listed by the
+// method policy, and evicted when their TTL expires or to make room for newer responses. Concurrent calls missing
+// the cache for the same key are coalesced into a single handler call, which runs until its deadline even if the
+// call that started it is canceled.
+//
+// Callers control caching with 'cache-control' metadata: no-cache skips the cached response, no-store skips
+// storing the response and max-age=<seconds> only accepts cached responses up to that age. Coalesced calls apply
+// their own directives. Responses carry an 'x-cache' header, HIT or MISS, and cached responses an 'age' header in
+// seconds.
+func UnaryCacheInterceptor(opts ...CacheOption) grpc.UnaryServerInterceptor {
+	o := &cacheOptions{
+		maxBytes: defaultCacheMaxBytes,
+		methods:  make(map[string]CachePolicy),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	responses := cache.NewLRU[proto.Message](o.maxBytes)
+	group := &singleflight.Group{}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		policy, ok := o.methods[info.FullMethod]
+		msg, isProto := req.(proto.Message)
+
+		if !ok || policy.TTL <= 0 || !isProto {
+			return handler(ctx, req)
+		}
+
+		key, err := cacheKey(ctx, info.FullMethod, msg, policy.Metadata)
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		directives := parseCacheControl(md.Get(string(constant.CacheControlHeader)))
+
+		if !directives.noCache {
+			resp, age, ok := responses.Get(key)
+			if ok && (!directives.hasMaxAge || age <= directives.maxAge) {
+				setCacheHeaders(ctx, cacheStatusHit, age)
+				return proto.Clone(resp), nil
+			}
+		}
+
+		setCacheHeaders(ctx, cacheStatusMiss, 0)
+
+		ch := group.DoChan(key, func() (interface{}, error) {
+			// The handler serves every coalesced call, so canceling the call that started it must not stop it
+			shared, cancel := detachCancel(ctx)
+			defer cancel()
+
+			return handler(shared, req)
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res := <-ch:
+			// The call that ran the handler timed out, so it has no response to share
+			if code := status.Code(res.Err); res.Shared && (code == codes.Canceled || code == codes.DeadlineExceeded) {
+				return handler(ctx, req)
+			}
+
+			m, ok := res.Val.(proto.Message)
+			if !ok || res.Err != nil {
+				return res.Val, res.Err
+			}
+
+			if !directives.noStore {
+				responses.Add(key, proto.Clone(m), proto.Size(m), policy.TTL)
+			}
+
+			if res.Shared {
+				return proto.Clone(m), nil
+			}
+
+			return m, nil
+		}
+	}
+}
+
+// detachCancel returns a context with the values and deadline of ctx, which is not canceled with it.
+func detachCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+
+	return detached, func() {}
+}
+
+// cacheKey returns the cache key of a call: a hash of the method, the caller, the deterministic encoding of the
+// request and the values of the metadata keys. Callers never share responses, which may depend on their identity.
+func cacheKey(ctx context.Context, method string, req proto.Message, keys []string) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(Principal(ctx)))
+	h.Write([]byte{0})
+	h.Write(data)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(strings.Join(md.Get(k), ",")))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func setCacheHeaders(ctx context.Context, cacheStatus string, age time.Duration) {
+	md := metadata.Pairs(string(constant.CacheStatusHeader), cacheStatus)
+	if cacheStatus == cacheStatusHit {
+		md.Append(string(constant.AgeHeader), strconv.Itoa(int(age.Seconds())))
+	}
+
+	// Outside of a gRPC call, e.g. in tests, there is no header to set
+	_ = grpc.SetHeader(ctx, md)
+}
@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+const cachedMethod = "/greeter.Greeter/GetGreeting"
+
+func TestParseCacheControl(t *testing.T) {
+	tests := []struct {
+		values   []string
+		expected cacheDirectives
+	}{
+		{values: nil, expected: cacheDirectives{}},
+		{values: []string{"no-cache"}, expected: cacheDirectives{noCache: true}},
+		{
+			values:   []string{"No-Store, max-age=30"},
+			expected: cacheDirectives{noStore: true, maxAge: 30 * time.Second, hasMaxAge: true},
+		},
+		{values: []string{"max-age=0"}, expected: cacheDirectives{hasMaxAge: true}},
+		{values: []string{"max-age=-1", "max-age=x", "private"}, expected: cacheDirectives{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, parseCacheControl(tt.values), tt.values)
+	}
+}
+
+func TestUnaryCacheInterceptor(t *testing.T) {
+	interceptor := UnaryCacheInterceptor(
+		WithCachedMethod(cachedMethod, CachePolicy{TTL: time.Minute, Metadata: []string{"accept-language"}}),
+	)
+
+	call := func(h *countingHandler, method, name string, md ...string) uint32 {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(md...))
+
+		resp, err := interceptor(ctx, &pb.HelloRequest{Name: name}, &grpc.UnaryServerInfo{FullMethod: method}, h.handle)
+		require.NoError(t, err)
+
+		return resp.(*pb.HelloReply).GetSequence()
+	}
+
+	h := &countingHandler{}
+
+	assert.Equal(t, uint32(1), call(h, cachedMethod, "Alice"))
+	assert.Equal(t, uint32(1), call(h, cachedMethod, "Alice"), "cached")
+	assert.Equal(t, uint32(2), call(h, cachedMethod, "Bob"), "other request")
+	assert.Equal(t, uint32(3), call(h, cachedMethod, "Alice", "accept-language", "fr"), "other metadata")
+	assert.Equal(t, uint32(1), call(h, cachedMethod, "Alice", "x-other", "ignored"), "metadata outside the key")
+	assert.Equal(t, uint32(4), call(h, "/greeter.Greeter/SayHello", "Alice"), "uncached method")
+	assert.Equal(t, uint32(5), call(h, "/greeter.Greeter/SayHello", "Alice"), "uncached method")
+
+	// no-cache refreshes the cached response
+	assert.Equal(t, uint32(6), call(h, cachedMethod, "Alice", "cache-control", "no-cache"))
+	assert.Equal(t, uint32(6), call(h, cachedMethod, "Alice"))
+
+	// no-store keeps the cached response
+	assert.Equal(t, uint32(7), call(h, cachedMethod, "Carol", "cache-control", "no-store"))
+	assert.Equal(t, uint32(8), call(h, cachedMethod, "Carol"))
+
+	// max-age rejects older responses
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, uint32(8), call(h, cachedMethod, "Carol", "cache-control", "max-age=60"))
+	assert.Equal(t, uint32(9), call(h, cachedMethod, "Carol", "cache-control", "max-age=0"))
+}
+
+func TestUnaryCacheInterceptor_ErrorsAreNotCached(t *testing.T) {
+	interceptor := UnaryCacheInterceptor(WithCachedMethod(cachedMethod, CachePolicy{TTL: time.Minute}))
+	h := &countingHandler{err: status.Error(codes.NotFound, "not found")}
+	info := &grpc.UnaryServerInfo{FullMethod: cachedMethod}
+
+	for range 2 {
+		_, err := interceptor(context.Background(), &pb.HelloRequest{Name: "Alice"}, info, h.handle)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	}
+
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestUnaryCacheInterceptor_Expiry(t *testing.T) {
+	interceptor := UnaryCacheInterceptor(WithCachedMethod(cachedMethod, CachePolicy{TTL: 20 * time.Millisecond}))
+	h := &countingHandler{}
+	info := &grpc.UnaryServerInfo{FullMethod: cachedMethod}
+
+	for range 2 {
+		_, err := interceptor(context.Background(), &pb.HelloRequest{Name: "Alice"}, info, h.handle)
+		require.NoError(t, err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	_, err := interceptor(context.Background(), &pb.HelloRequest{Name: "Alice"}, info, h.handle)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestUnaryCacheInterceptor_CoalescesMisses(t *testing.T) {
+	interceptor := UnaryCacheInterceptor(WithCachedMethod(cachedMethod, CachePolicy{TTL: time.Minute}))
+	h := &countingHandler{release: make(chan struct{})}
+	info := &grpc.UnaryServerInfo{FullMethod: cachedMethod}
+
+	const callers = 5
+
+	var wg sync.WaitGroup
+
+	replies := make([]*pb.HelloReply, callers)
+
+	for i := range callers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := interceptor(context.Background(), &pb.HelloRequest{Name: "Alice"}, info, h.handle)
+			assert.NoError(t, err)
+
+			replies[i], _ = resp.(*pb.HelloReply)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return h.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Give the other callers time to join the call in progress
+	time.Sleep(20 * time.Millisecond)
+	close(h.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), h.calls.Load())
+
+	for _, reply := range replies {
+		assert.Equal(t, uint32(1), reply.GetSequence())
+	}
+
+	// Callers get their own copy of the response
+	replies[0].Message = "changed"
+	assert.NotEqual(t, "changed", replies[1].GetMessage())
+}
+
+func TestUnaryCacheInterceptor_ScopedByCaller(t *testing.T) {
+	interceptor := UnaryCacheInterceptor(WithCachedMethod(cachedMethod, CachePolicy{TTL: time.Minute}))
+	h := &countingHandler{}
+	info := &grpc.UnaryServerInfo{FullMethod: cachedMethod}
+
+	caller := func(name string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+				VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: name}}}},
+			}},
+		})
+	}
+
+	call := func(ctx context.Context) uint32 {
+		resp, err := interceptor(ctx, &pb.HelloRequest{Name: "Alice"}, info, h.handle)
+		require.NoError(t, err)
+
+		return resp.(*pb.HelloReply).GetSequence()
+	}
+
+	assert.Equal(t, uint32(1), call(caller("alice")))
+	assert.Equal(t, uint32(1), call(caller("alice")), "cached")
+	assert.Equal(t, uint32(2), call(caller("bob")), "other caller")
+	assert.Equal(t, uint32(3), call(context.Background()), "anonymous caller")
+}
+
+func TestUnaryCacheInterceptor_CoalescedCallerCanceled(t *testing.T) {
+	interceptor := UnaryCacheInterceptor(WithCachedMethod(cachedMethod, CachePolicy{TTL: time.Minute}))
+	info := &grpc.UnaryServerInfo{FullMethod: cachedMethod}
+
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		n := calls.Add(1)
+
+		<-release
+
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+
+		return &pb.HelloReply{Sequence: uint32(n)}, nil
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+
+	go func() {
+		_, err := interceptor(first, &pb.HelloRequest{Name: "Alice"}, info, handler)
+		firstErr <- err
+	}()
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan *pb.HelloReply, 1)
+
+	go func() {
+		resp, err := interceptor(context.Background(), &pb.HelloRequest{Name: "Alice"}, info, handler)
+		assert.NoError(t, err)
+
+		reply, _ := resp.(*pb.HelloReply)
+		second <- reply
+	}()
+
+	// Give the second caller time to join the call in progress, then cancel the call that started it
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	close(release)
+
+	assert.Equal(t, uint32(1), (<-second).GetSequence())
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestUnaryCacheInterceptor_CoalescedNoStore(t *testing.T) {
+	interceptor := UnaryCacheInterceptor(WithCachedMethod(cachedMethod, CachePolicy{TTL: time.Minute}))
+	h := &countingHandler{release: make(chan struct{})}
+	info := &grpc.UnaryServerInfo{FullMethod: cachedMethod}
+
+	call := func(md ...string) uint32 {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(md...))
+
+		resp, err := interceptor(ctx, &pb.HelloRequest{Name: "Alice"}, info, h.handle)
+		assert.NoError(t, err)
+
+		reply, _ := resp.(*pb.HelloReply)
+
+		return reply.GetSequence()
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		assert.Equal(t, uint32(1), call("cache-control", "no-store"))
+	}()
+
+	require.Eventually(t, func() bool { return h.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	go func() {
+		defer wg.Done()
+		assert.Equal(t, uint32(1), call())
+	}()
+
+	// Give the second caller time to join the call in progress
+	time.Sleep(20 * time.Millisecond)
+	close(h.release)
+	wg.Wait()
+
+	// no-store only applies to the caller sending it: the response shared with the second caller is stored
+	assert.Equal(t, uint32(1), call())
+	assert.Equal(t, int32(1), h.calls.Load())
+}
@@ -0,0 +1,302 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+)
+
+const (
+	// deadlineSourceClient marks a deadline set by the client within the allowed maximum.
+	deadlineSourceClient = "client"
+	// deadlineSourceDefault marks a deadline applied because the client sent none.
+	deadlineSourceDefault = "default"
+	// deadlineSourceClamped marks a client deadline reduced to the allowed maximum.
+	deadlineSourceClamped = "clamped"
+	// deadlineSourceNone marks a call running without any deadline.
+	deadlineSourceNone = "none"
+)
+
+var (
+	// ErrStreamIdle is returned when the client sends no message on a stream for longer than its idle timeout.
+	ErrStreamIdle = grpcerr.New(codes.DeadlineExceeded, "STREAM_IDLE_TIMEOUT", "stream idle timeout exceeded")
+	// ErrStreamMaxDuration is returned when a stream stays open longer than its maximum duration.
+	ErrStreamMaxDuration = grpcerr.New(codes.DeadlineExceeded, "STREAM_MAX_DURATION",
+		"stream exceeded the maximum duration")
+)
+
+// DeadlinePolicy is the timeout policy of a method. Zero values disable the corresponding limit.
+type DeadlinePolicy struct {
+	// Default is the timeout applied when the client sends no deadline.
+	Default time.Duration
+	// Max is the longest timeout a client may ask for. Longer client deadlines are clamped.
+	Max time.Duration
+	// StreamIdle cancels a client stream when the client sends no message for this long.
+	StreamIdle time.Duration
+	// StreamMax cancels a stream once it has been open for this long, whatever the deadline of the client.
+	StreamMax time.Duration
+}
+
+// DeadlineOption configures the deadline interceptors.
+type DeadlineOption func(*deadlineOptions)
+
+type deadlineOptions struct {
+	policy  DeadlinePolicy
+	methods map[string]DeadlinePolicy
+}
+
+// WithDefaultDeadlinePolicy sets the policy of every method without a policy of its own.
+func WithDefaultDeadlinePolicy(p DeadlinePolicy) DeadlineOption {
+	return func(o *deadlineOptions) {
+		o.policy = p
+	}
+}
+
+// WithMethodDeadlinePolicy sets the policy of the given full method name, e.g. "/greeter.Greeter/SayHello".
+func WithMethodDeadlinePolicy(method string, p DeadlinePolicy) DeadlineOption {
+	return func(o *deadlineOptions) {
+		o.methods[method] = p
+	}
+}
+
+func newDeadlineOptions(opts []DeadlineOption) *deadlineOptions {
+	o := &deadlineOptions{methods: make(map[string]DeadlinePolicy)}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+func (o *deadlineOptions) policyFor(fullMethod string) DeadlinePolicy {
+	if p, ok := o.methods[fullMethod]; ok {
+		return p
+	}
+
+	return o.policy
+}
+
+// apply derives the context enforcing the policy and injects the remaining budget in the log fields.
+func (p DeadlinePolicy) apply(ctx context.Context) (context.Context, context.CancelFunc) {
+	source := deadlineSourceNone
+	cancel := context.CancelFunc(func() {})
+
+	deadline, ok := ctx.Deadline()
+
+	switch {
+	case !ok && p.Default > 0:
+		source = deadlineSourceDefault
+		ctx, cancel = context.WithTimeout(ctx, p.Default)
+	case ok && p.Max > 0 && time.Until(deadline) > p.Max:
+		source = deadlineSourceClamped
+		ctx, cancel = context.WithTimeout(ctx, p.Max)
+	case ok:
+		source = deadlineSourceClient
+	}
+
+	fields := logging.Fields{"grpc.deadline.source", source}
+	if deadline, ok := ctx.Deadline(); ok {
+		fields = append(fields, "grpc.deadline.budget_ms", time.Until(deadline).Milliseconds())
+	}
+
+	return logging.InjectFields(ctx, fields), cancel
+}
+
+// UnaryDeadlineInterceptor returns a new unary server interceptor enforcing the deadline policy of each method.
+// Calls without a deadline get the default timeout, and client deadlines above the maximum are clamped.
+// The deadline source and remaining budget are added to the log fields, so it must run before the logging
+// interceptor.
+func UnaryDeadlineInterceptor(opts ...DeadlineOption) grpc.UnaryServerInterceptor {
+	o := newDeadlineOptions(opts)
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, cancel := o.policyFor(info.FullMethod).apply(ctx)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamDeadlineInterceptor returns a new stream server interceptor canceling client streams with ErrStreamIdle
+// once the client sent no message for the idle timeout, and every stream with ErrStreamMaxDuration once it has
+// been open for the maximum duration. The default and maximum timeouts only apply to streams of methods with a
+// policy of their own: long-lived streams such as Chat would otherwise be cut off after the unary timeout.
+//
+// Once the stream is canceled, receiving and sending fail with its status, so that the handler returns it.
+func StreamDeadlineInterceptor(opts ...DeadlineOption) grpc.StreamServerInterceptor {
+	o := newDeadlineOptions(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		policy := o.streamPolicyFor(info.FullMethod)
+
+		ctx, cancelDeadline := policy.apply(ss.Context())
+		defer cancelDeadline()
+
+		if policy.StreamMax > 0 {
+			var cancelMax context.CancelFunc
+
+			ctx, cancelMax = context.WithTimeoutCause(ctx, policy.StreamMax, ErrStreamMaxDuration)
+			defer cancelMax()
+		}
+
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		ds := &deadlineStream{ServerStream: ss, ctx: ctx}
+		defer ds.close()
+
+		// Clients of server streams send a single request, so only client streams can be idle
+		if policy.StreamIdle > 0 && info.IsClientStream {
+			ds.idle = policy.StreamIdle
+			ds.timer = time.AfterFunc(policy.StreamIdle, func() { cancel(ErrStreamIdle) })
+
+			defer ds.timer.Stop()
+		}
+
+		return handler(srv, ds)
+	}
+}
+
+// streamPolicyFor returns the policy of a stream: the policy of the method, or only the stream limits of the
+// default policy.
+func (o *deadlineOptions) streamPolicyFor(fullMethod string) DeadlinePolicy {
+	if p, ok := o.methods[fullMethod]; ok {
+		return p
+	}
+
+	return DeadlinePolicy{StreamIdle: o.policy.StreamIdle, StreamMax: o.policy.StreamMax}
+}
+
+// received is a message read by the receiver of a deadlineStream.
+type received struct {
+	msg proto.Message
+	err error
+}
+
+// deadlineStream wraps a grpc.ServerStream so that messages fail once the derived context ended, and every
+// message received resets the idle timer. Messages sent do not: a client only receiving is idle.
+//
+// A canceled context does not interrupt a receive of the underlying stream, which only returns once gRPC closed
+// the stream after the handler returned. Messages are therefore read by a single receiver goroutine, into
+// messages of its own: RecvMsg returns as soon as the context ends, and the receive left pending never writes to
+// a message of the handler.
+type deadlineStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	idle  time.Duration
+	timer *time.Timer
+
+	// requests hands the message to read into to the receiver, started by the first RecvMsg
+	requests chan proto.Message
+	results  chan received
+	// err is the error ending the receiver, returned by every later RecvMsg
+	err error
+}
+
+func (s *deadlineStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *deadlineStream) touch() {
+	if s.timer == nil {
+		return
+	}
+
+	s.timer.Reset(s.idle)
+}
+
+func (s *deadlineStream) SendMsg(m interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return contextStatus(s.ctx)
+	}
+
+	return s.ServerStream.SendMsg(m)
+}
+
+// RecvMsg waits for the next message or for the derived context to end, whichever comes first.
+func (s *deadlineStream) RecvMsg(m interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return contextStatus(s.ctx)
+	}
+
+	if s.err != nil {
+		return s.err
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return s.ServerStream.RecvMsg(m)
+	}
+
+	if s.requests == nil {
+		s.requests = make(chan proto.Message)
+		s.results = make(chan received, 1)
+
+		go s.receive()
+	}
+
+	s.requests <- msg.ProtoReflect().New().Interface()
+
+	select {
+	case r := <-s.results:
+		if r.err != nil {
+			s.err = r.err
+			return r.err
+		}
+
+		proto.Reset(msg)
+		proto.Merge(msg, r.msg)
+		s.touch()
+
+		return nil
+	case <-s.ctx.Done():
+		return contextStatus(s.ctx)
+	}
+}
+
+// receive reads a message for every request, until the stream fails or is closed.
+func (s *deadlineStream) receive() {
+	for msg := range s.requests {
+		err := s.ServerStream.RecvMsg(msg)
+		s.results <- received{msg: msg, err: err}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// close stops the receiver once the handler returned.
+func (s *deadlineStream) close() {
+	if s.requests != nil {
+		close(s.requests)
+	}
+}
+
+// contextStatus converts the end of a stream context into a status error.
+func contextStatus(ctx context.Context) error {
+	cause := context.Cause(ctx)
+
+	switch {
+	case errors.Is(cause, ErrStreamIdle), errors.Is(cause, ErrStreamMaxDuration):
+		return cause
+	case errors.Is(cause, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	default:
+		return status.Error(codes.Canceled, "stream canceled")
+	}
+}
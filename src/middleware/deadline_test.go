@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestDeadlinePolicy_Apply(t *testing.T) {
+	policy := DeadlinePolicy{Default: time.Second, Max: time.Minute}
+
+	withTimeout := func(d time.Duration) context.Context {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		t.Cleanup(cancel)
+
+		return ctx
+	}
+
+	tests := []struct {
+		name      string
+		policy    DeadlinePolicy
+		ctx       context.Context
+		source    string
+		maxBudget time.Duration
+	}{
+		{name: "default applied", policy: policy, ctx: context.Background(), source: "default", maxBudget: time.Second},
+		{
+			name: "client kept", policy: policy, ctx: withTimeout(10 * time.Second),
+			source: "client", maxBudget: 10 * time.Second,
+		},
+		{name: "client clamped", policy: policy, ctx: withTimeout(time.Hour), source: "clamped", maxBudget: time.Minute},
+		{name: "no policy", ctx: context.Background(), source: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.policy.apply(tt.ctx)
+			defer cancel()
+
+			fields := logging.ExtractFields(ctx)
+			iter := fields.Iterator()
+
+			values := map[string]any{}
+			for iter.Next() {
+				k, v := iter.At()
+				values[k] = v
+			}
+
+			assert.Equal(t, tt.source, values["grpc.deadline.source"])
+
+			deadline, ok := ctx.Deadline()
+			if tt.maxBudget == 0 {
+				assert.False(t, ok)
+				assert.NotContains(t, values, "grpc.deadline.budget_ms")
+
+				return
+			}
+
+			require.True(t, ok)
+			assert.LessOrEqual(t, time.Until(deadline), tt.maxBudget)
+			assert.Greater(t, time.Until(deadline), tt.maxBudget-time.Second)
+			assert.Contains(t, values, "grpc.deadline.budget_ms")
+		})
+	}
+}
+
+// blockingServerStream is a grpc.ServerStream receiving its messages, then blocking until its context ends, as
+// gRPC does until it closes the stream.
+type blockingServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	messages []string
+}
+
+func (b *blockingServerStream) Context() context.Context { return b.ctx }
+
+func (b *blockingServerStream) RecvMsg(m interface{}) error {
+	if len(b.messages) > 0 {
+		m.(*wrapperspb.StringValue).Value, b.messages = b.messages[0], b.messages[1:]
+		return nil
+	}
+
+	<-b.ctx.Done()
+
+	return b.ctx.Err()
+}
+
+func TestStreamDeadlineInterceptor(t *testing.T) {
+	const method = "/greeter.Greeter/Chat"
+
+	tests := []struct {
+		name    string
+		opts    []DeadlineOption
+		reason  string
+		message string
+	}{
+		{
+			name:    "idle timeout",
+			opts:    []DeadlineOption{WithDefaultDeadlinePolicy(DeadlinePolicy{StreamIdle: 20 * time.Millisecond})},
+			reason:  "STREAM_IDLE_TIMEOUT",
+			message: "stream idle timeout exceeded",
+		},
+		{
+			name:    "max duration",
+			opts:    []DeadlineOption{WithDefaultDeadlinePolicy(DeadlinePolicy{StreamMax: 20 * time.Millisecond})},
+			reason:  "STREAM_MAX_DURATION",
+			message: "stream exceeded the maximum duration",
+		},
+		{
+			name:    "method deadline",
+			opts:    []DeadlineOption{WithMethodDeadlinePolicy(method, DeadlinePolicy{Default: 20 * time.Millisecond})},
+			message: "deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streamCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			interceptor := StreamDeadlineInterceptor(tt.opts...)
+			ss := &blockingServerStream{ctx: streamCtx, messages: []string{"hello"}}
+			info := &grpc.StreamServerInfo{FullMethod: method, IsClientStream: true}
+
+			err := interceptor(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+				first := &wrapperspb.StringValue{}
+				require.NoError(t, stream.RecvMsg(first))
+				assert.Equal(t, "hello", first.GetValue())
+
+				// The pending receive returns as soon as the stream is canceled, leaving the message untouched
+				second := &wrapperspb.StringValue{Value: "untouched"}
+				err := stream.RecvMsg(second)
+				assert.Equal(t, "untouched", second.GetValue())
+
+				// Later messages fail with the same status
+				assert.Equal(t, err, stream.RecvMsg(second))
+				assert.Equal(t, err, stream.SendMsg(first))
+
+				return err
+			})
+
+			st := status.Convert(err)
+			assert.Equal(t, codes.DeadlineExceeded, st.Code())
+			assert.Equal(t, tt.message, st.Message())
+
+			if tt.reason != "" {
+				require.NotEmpty(t, st.Details())
+				assert.Equal(t, tt.reason, st.Details()[0].(*errdetails.ErrorInfo).GetReason())
+			}
+		})
+	}
+}
+
+func TestStreamDeadlineInterceptor_OutlivesDefault(t *testing.T) {
+	interceptor := StreamDeadlineInterceptor(WithDefaultDeadlinePolicy(DeadlinePolicy{
+		Default:    20 * time.Millisecond,
+		Max:        20 * time.Millisecond,
+		StreamIdle: time.Second,
+	}))
+	ss := &blockingServerStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/greeter.Greeter/Chat", IsClientStream: true}
+
+	err := interceptor(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+		_, ok := stream.Context().Deadline()
+		assert.False(t, ok)
+
+		time.Sleep(60 * time.Millisecond)
+
+		return stream.Context().Err()
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestStreamDeadlineInterceptor_ServerStream(t *testing.T) {
+	// Clients of server streams cannot send, so they are never idle
+	interceptor := StreamDeadlineInterceptor(WithDefaultDeadlinePolicy(DeadlinePolicy{StreamIdle: 20 * time.Millisecond}))
+	ss := &blockingServerStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/greeter.Greeter/StreamGreetings", IsServerStream: true}
+
+	err := interceptor(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+		time.Sleep(60 * time.Millisecond)
+		return stream.Context().Err()
+	})
+
+	assert.NoError(t, err)
+}
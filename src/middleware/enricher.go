@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Call describes the incoming call being enriched.
+type Call struct {
+	// FullMethod is the full RPC method string, i.e., /package.service/method.
+	FullMethod string
+	// IsStream reports whether the call is a streaming RPC.
+	IsStream bool
+	// SetHeader sets response header metadata for the call.
+	SetHeader func(md metadata.MD) error
+	// SetTrailer sets response trailer metadata for the call.
+	SetTrailer func(md metadata.MD) error
+}
+
+// ContextEnricher derives the context of an incoming call before its handler runs. A single enricher
+// produces both a unary and a stream server interceptor, see EnricherInterceptors.
+// Returning an error fails the call without running the handler.
+type ContextEnricher interface {
+	Enrich(ctx context.Context, call *Call) (context.Context, error)
+}
+
+// CallFinisher is implemented by a ContextEnricher that needs to act once the handler has returned,
+// e.g. to set trailers or decorate the returned error. The returned error replaces the handler error.
+type CallFinisher interface {
+	Finish(ctx context.Context, call *Call, err error) error
+}
+
+// ContextEnricherFunc is an adapter to allow the use of ordinary functions as a ContextEnricher.
+type ContextEnricherFunc func(ctx context.Context, call *Call) (context.Context, error)
+
+// Enrich calls f(ctx, call).
+func (f ContextEnricherFunc) Enrich(ctx context.Context, call *Call) (context.Context, error) {
+	return f(ctx, call)
+}
+
+// UnaryEnricherInterceptor returns a new unary server interceptor that runs the handler with the enriched context.
+func UnaryEnricherInterceptor(e ContextEnricher) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		call := &Call{
+			FullMethod: info.FullMethod,
+			SetHeader:  func(md metadata.MD) error { return grpc.SetHeader(ctx, md) },
+			SetTrailer: func(md metadata.MD) error { return grpc.SetTrailer(ctx, md) },
+		}
+
+		newCtx, err := e.Enrich(ctx, call)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(newCtx, req)
+
+		if f, ok := e.(CallFinisher); ok {
+			err = f.Finish(newCtx, call, err)
+		}
+
+		return resp, err
+	}
+}
+
+// StreamEnricherInterceptor returns a new stream server interceptor that runs the handler with a stream whose
+// context is the enriched context.
+func StreamEnricherInterceptor(e ContextEnricher) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		call := &Call{
+			FullMethod: info.FullMethod,
+			IsStream:   true,
+			SetHeader:  ss.SetHeader,
+			SetTrailer: func(md metadata.MD) error {
+				ss.SetTrailer(md)
+				return nil
+			},
+		}
+
+		newCtx, err := e.Enrich(ss.Context(), call)
+		if err != nil {
+			return err
+		}
+
+		err = handler(srv, &wrappedStream{ServerStream: ss, newCtx: newCtx})
+
+		if f, ok := e.(CallFinisher); ok {
+			err = f.Finish(newCtx, call, err)
+		}
+
+		return err
+	}
+}
+
+// EnricherInterceptors returns the unary and stream server interceptors of the enricher.
+func EnricherInterceptors(e ContextEnricher) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	return UnaryEnricherInterceptor(e), StreamEnricherInterceptor(e)
+}
+
+// wrappedStream wraps a grpc.ServerStream and overrides its context.
+type wrappedStream struct {
+	grpc.ServerStream
+	newCtx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.newCtx
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type enricherKey struct{}
+
+// mockServerStream is a minimal grpc.ServerStream recording the headers set on it.
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (m *mockServerStream) Context() context.Context { return m.ctx }
+
+func (m *mockServerStream) SetHeader(md metadata.MD) error {
+	m.header = metadata.Join(m.header, md)
+	return nil
+}
+
+func (m *mockServerStream) SetTrailer(md metadata.MD) {
+	m.trailer = metadata.Join(m.trailer, md)
+}
+
+func TestStreamEnricherInterceptor(t *testing.T) {
+	var seen *Call
+
+	enricher := ContextEnricherFunc(func(ctx context.Context, call *Call) (context.Context, error) {
+		seen = call
+		require.NoError(t, call.SetHeader(metadata.Pairs("x-enriched", "yes")))
+
+		return context.WithValue(ctx, enricherKey{}, "value"), nil
+	})
+
+	ss := &mockServerStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/greeter.Greeter/Chat"}
+
+	err := StreamEnricherInterceptor(enricher)(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+		assert.Equal(t, "value", stream.Context().Value(enricherKey{}))
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "/greeter.Greeter/Chat", seen.FullMethod)
+	assert.True(t, seen.IsStream)
+	assert.Equal(t, []string{"yes"}, ss.header.Get("x-enriched"))
+}
+
+func TestUnaryEnricherInterceptor_Error(t *testing.T) {
+	errEnrich := errors.New("enrich failed")
+	enricher := ContextEnricherFunc(func(context.Context, *Call) (context.Context, error) {
+		return nil, errEnrich
+	})
+
+	called := false
+	info := &grpc.UnaryServerInfo{FullMethod: "/greeter.Greeter/SayHello"}
+
+	_, err := UnaryEnricherInterceptor(enricher)(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+
+	assert.ErrorIs(t, err, errEnrich)
+	assert.False(t, called)
+}
+
+func TestStreamRequestIDInterceptor(t *testing.T) {
+	ss := &mockServerStream{ctx: incomingRequestIDContext("abc-123")}
+	info := &grpc.StreamServerInfo{FullMethod: "/greeter.Greeter/Chat"}
+
+	err := StreamRequestIDInterceptor()(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+		id, ok := RequestIDFromContext(stream.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "abc-123", id)
+
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"abc-123"}, ss.header.Get("x-request-id"))
+	assert.Equal(t, []string{"abc-123"}, ss.trailer.Get("x-request-id"))
+}
+
+func TestStreamRequestIDInterceptor_ErrorDetails(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{name: "status error", err: status.Error(codes.NotFound, "not found"), code: codes.NotFound},
+		{name: "plain error", err: errors.New("boom"), code: codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ss := &mockServerStream{ctx: incomingRequestIDContext("abc-123")}
+			info := &grpc.StreamServerInfo{FullMethod: "/greeter.Greeter/Chat"}
+
+			err := StreamRequestIDInterceptor()(nil, ss, info, func(interface{}, grpc.ServerStream) error {
+				return tt.err
+			})
+
+			st := status.Convert(err)
+			assert.Equal(t, tt.code, st.Code())
+			require.Len(t, st.Details(), 1)
+
+			requestInfo, ok := st.Details()[0].(*errdetails.RequestInfo)
+			require.True(t, ok)
+			assert.Equal(t, "abc-123", requestInfo.GetRequestId())
+			assert.Equal(t, []string{"abc-123"}, ss.trailer.Get("x-request-id"))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+)
+
+// FlagAttributesEnricher is a ContextEnricher that adds the attributes feature flags are evaluated against to the
+// context: the full method, the principal of the caller and the request ID. It must run after the request ID
+// interceptors.
+var FlagAttributesEnricher = ContextEnricherFunc(func(ctx context.Context, call *Call) (context.Context, error) {
+	a := flags.Attributes{Method: call.FullMethod}
+
+	if p := Principal(ctx); p != anonymousPrincipal {
+		a.Principal = p
+	}
+
+	a.RequestID, _ = RequestIDFromContext(ctx)
+
+	return flags.ContextWithAttributes(ctx, a), nil
+})
+
+// UnaryFlagsInterceptor returns a new unary server interceptor adding the feature flag attributes to the context.
+// See FlagAttributesEnricher for details.
+func UnaryFlagsInterceptor() grpc.UnaryServerInterceptor {
+	return UnaryEnricherInterceptor(FlagAttributesEnricher)
+}
+
+// StreamFlagsInterceptor returns a new stream server interceptor adding the feature flag attributes to the
+// context. See FlagAttributesEnricher for details.
+func StreamFlagsInterceptor() grpc.StreamServerInterceptor {
+	return StreamEnricherInterceptor(FlagAttributesEnricher)
+}
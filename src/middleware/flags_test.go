@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+)
+
+func TestUnaryFlagsInterceptor(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want flags.Attributes
+	}{
+		{
+			name: "anonymous",
+			ctx:  ContextWithRequestID(context.Background(), "req-1"),
+			want: flags.Attributes{Method: "/greeter.Greeter/SayHello", RequestID: "req-1"},
+		},
+		{
+			name: "verified client certificate",
+			ctx: peer.NewContext(ContextWithRequestID(context.Background(), "req-2"), &peer.Peer{
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "alice"}}}},
+				}},
+			}),
+			want: flags.Attributes{Method: "/greeter.Greeter/SayHello", Principal: "alice", RequestID: "req-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &grpc.UnaryServerInfo{FullMethod: "/greeter.Greeter/SayHello"}
+
+			_, err := UnaryFlagsInterceptor()(tt.ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				a, ok := flags.AttributesFromContext(ctx)
+				require.True(t, ok)
+				assert.Equal(t, tt.want, a)
+
+				return nil, nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
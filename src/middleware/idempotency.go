@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+	"github.com/mrityunjoydey/go-grpc/pkg/idempotency"
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+	"github.com/mrityunjoydey/go-grpc/src/common/constant"
+)
+
+const (
+	// defaultIdempotencyTTL is the default time an outcome is replayed for.
+	defaultIdempotencyTTL = 24 * time.Hour
+	// defaultIdempotencyKeyMaxLength is the default maximum length of an idempotency key.
+	defaultIdempotencyKeyMaxLength = 128
+)
+
+var (
+	// ErrInvalidIdempotencyKey is returned when the idempotency key is too long or has characters outside
+	// [A-Za-z0-9._:-].
+	ErrInvalidIdempotencyKey = grpcerr.InvalidArgument("INVALID_IDEMPOTENCY_KEY", "invalid idempotency key")
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key is reused for a different request.
+	ErrIdempotencyKeyReused = grpcerr.InvalidArgument("IDEMPOTENCY_KEY_REUSED",
+		"idempotency key was already used for a different request")
+)
+
+// replayedCodes are the status codes whose outcome is recorded. Other failures are transient, so retrying
+// the call with the same key runs it again.
+var replayedCodes = map[codes.Code]bool{
+	codes.OK:                 true,
+	codes.InvalidArgument:    true,
+	codes.NotFound:           true,
+	codes.AlreadyExists:      true,
+	codes.FailedPrecondition: true,
+	codes.OutOfRange:         true,
+	codes.Unimplemented:      true,
+}
+
+// IdempotencyOption configures the idempotency interceptor.
+type IdempotencyOption func(*idempotencyOptions)
+
+type idempotencyOptions struct {
+	ttl          time.Duration
+	keyMaxLength int
+}
+
+// WithIdempotencyTTL sets how long the outcome of a call is replayed to calls with the same key.
+func WithIdempotencyTTL(d time.Duration) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.ttl = d
+	}
+}
+
+// WithIdempotencyKeyMaxLength sets the maximum length of an idempotency key.
+func WithIdempotencyKeyMaxLength(n int) IdempotencyOption {
+	return func(o *idempotencyOptions) {
+		o.keyMaxLength = n
+	}
+}
+
+// idempotentCall is a call in progress, awaited by the concurrent calls with the same key.
+type idempotentCall struct {
+	fingerprint string
+	done        chan struct{}
+	resp        proto.Message
+	err         error
+	// replayable is false when err is not the outcome of the call, e.g. when the key was reused.
+	replayable bool
+}
+
+// idempotencyTracker records call outcomes and tracks the calls in progress.
+type idempotencyTracker struct {
+	store  idempotency.Store
+	logger logger.Logger
+	opts   idempotencyOptions
+
+	mu    sync.Mutex
+	calls map[string]*idempotentCall
+}
+
+// UnaryIdempotencyInterceptor returns a new unary server interceptor that makes calls carrying an
+// 'idempotency-key' metadata header idempotent. The outcome of the first call with a key is recorded in the
+// store per method, caller and key, and replayed to the calls with the same key until it expires, with an
+// 'idempotent-replayed' response header. Concurrent calls with the same key wait for the first one, and a key
+// reused for a different request fails with codes.InvalidArgument. Transient failures, such as Unavailable or
+// DeadlineExceeded, are not recorded. Failures of the store are logged and the call runs normally.
+func UnaryIdempotencyInterceptor(
+	store idempotency.Store,
+	l logger.Logger,
+	opts ...IdempotencyOption,
+) grpc.UnaryServerInterceptor {
+	t := &idempotencyTracker{
+		store:  store,
+		logger: l,
+		opts: idempotencyOptions{
+			ttl:          defaultIdempotencyTTL,
+			keyMaxLength: defaultIdempotencyKeyMaxLength,
+		},
+		calls: make(map[string]*idempotentCall),
+	}
+
+	for _, opt := range opts {
+		opt(&t.opts)
+	}
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		key := firstMetadataValue(ctx, constant.IdempotencyKeyHeader)
+		msg, ok := req.(proto.Message)
+
+		if key == "" || !ok {
+			return handler(ctx, req)
+		}
+
+		if len(key) > t.opts.keyMaxLength || !requestIDCharset.MatchString(key) {
+			return nil, ErrInvalidIdempotencyKey.WithMetadata("max_length", strconv.Itoa(t.opts.keyMaxLength))
+		}
+
+		fingerprint, err := idempotency.Fingerprint(msg)
+		if err != nil {
+			return nil, grpcerr.Internal("internal server error").Wrap(err)
+		}
+
+		// Scope the key to the method and caller. Neither the method nor the key contain '|', so the principal,
+		// which may, goes last
+		scoped := strings.Join([]string{info.FullMethod, key, Principal(ctx)}, "|")
+
+		return t.do(ctx, scoped, fingerprint, func() (interface{}, error) {
+			return handler(ctx, req)
+		})
+	}
+}
+
+// do runs the call, unless a concurrent or recorded call with the same key exists.
+func (t *idempotencyTracker) do(
+	ctx context.Context,
+	key, fingerprint string,
+	run func() (interface{}, error),
+) (interface{}, error) {
+	for {
+		t.mu.Lock()
+
+		call, ok := t.calls[key]
+		if !ok {
+			// Concurrent calls fail with Internal if the handler panics before recording an outcome
+			call = &idempotentCall{
+				fingerprint: fingerprint,
+				done:        make(chan struct{}),
+				err:         grpcerr.Internal("internal server error"),
+			}
+			t.calls[key] = call
+			t.mu.Unlock()
+
+			return t.lead(ctx, key, fingerprint, call, run)
+		}
+
+		t.mu.Unlock()
+
+		if call.fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if !call.replayable {
+			return nil, call.err
+		}
+
+		// A transient failure is not the outcome of the call: run it again, as a retry with the key would
+		if !replayedCodes[status.Code(call.err)] {
+			continue
+		}
+
+		return replay(ctx, call.resp, call.err)
+	}
+}
+
+// lead runs the call for the concurrent calls with the same key, replaying its recorded outcome if any.
+func (t *idempotencyTracker) lead(
+	ctx context.Context,
+	key, fingerprint string,
+	call *idempotentCall,
+	run func() (interface{}, error),
+) (interface{}, error) {
+	defer func() {
+		t.mu.Lock()
+		delete(t.calls, key)
+		t.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	log := t.logger.WithContext(ctx)
+
+	record, err := t.store.Get(ctx, key)
+
+	switch {
+	case err == nil && record.Fingerprint != fingerprint:
+		call.err = ErrIdempotencyKeyReused
+		return nil, call.err
+	case err == nil:
+		resp, st, err := record.Decode()
+		if err != nil {
+			log.Error("failed to decode idempotency record", zap.Error(err))
+			break
+		}
+
+		call.resp, call.err, call.replayable = resp, st.Err(), true
+
+		return replay(ctx, call.resp, call.err)
+	case !errors.Is(err, idempotency.ErrNotFound):
+		log.Error("failed to read idempotency record", zap.Error(err))
+	}
+
+	result, err := run()
+	resp, _ := result.(proto.Message)
+
+	call.resp, call.err, call.replayable = resp, err, true
+
+	if !replayedCodes[status.Code(err)] || (err == nil && resp == nil) {
+		return result, err
+	}
+
+	record, rerr := idempotency.NewRecord(fingerprint, resp, err, t.opts.ttl)
+	if rerr == nil {
+		rerr = t.store.Put(ctx, key, record)
+	}
+
+	if rerr != nil {
+		log.Error("failed to write idempotency record", zap.Error(rerr))
+	}
+
+	return result, err
+}
+
+// replay returns a recorded outcome, marking the response as replayed.
+func replay(ctx context.Context, resp proto.Message, err error) (interface{}, error) {
+	// Outside of a gRPC call, e.g. in tests, there is no header to set
+	_ = grpc.SetHeader(ctx, metadata.Pairs(string(constant.IdempotentReplayedHeader), "true"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Clone(resp), nil
+}
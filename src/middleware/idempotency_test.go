@@ -0,0 +1,275 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/idempotency"
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+// countingHandler counts its calls and replies with the call number, or fails with the given error.
+type countingHandler struct {
+	calls   atomic.Int32
+	err     error
+	release chan struct{}
+}
+
+func (h *countingHandler) handle(_ context.Context, req interface{}) (interface{}, error) {
+	n := h.calls.Add(1)
+
+	if h.release != nil {
+		<-h.release
+	}
+
+	if h.err != nil {
+		return nil, h.err
+	}
+
+	return &pb.HelloReply{Message: "Hello, " + req.(*pb.HelloRequest).GetName(), Sequence: uint32(n)}, nil
+}
+
+func withIdempotencyKey(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("idempotency-key", key))
+}
+
+func TestUnaryIdempotencyInterceptor(t *testing.T) {
+	log, _ := logger.NewZapLogger("test", false)
+	info := &grpc.UnaryServerInfo{FullMethod: "/greeter.Greeter/SayHello"}
+
+	tests := []struct {
+		name          string
+		handlerErr    error
+		firstKey      string
+		secondKey     string
+		secondReq     *pb.HelloRequest
+		expectedCalls int32
+		expectedCode  codes.Code
+		expectedErr   error
+	}{
+		{
+			name:          "same key replays the response",
+			firstKey:      "key-1",
+			secondKey:     "key-1",
+			expectedCalls: 1,
+		},
+		{
+			name:          "different keys run twice",
+			firstKey:      "key-1",
+			secondKey:     "key-2",
+			expectedCalls: 2,
+		},
+		{
+			name:          "calls without a key run twice",
+			expectedCalls: 2,
+		},
+		{
+			name:          "recorded failures are replayed",
+			handlerErr:    status.Error(codes.NotFound, "not found"),
+			firstKey:      "key-1",
+			secondKey:     "key-1",
+			expectedCalls: 1,
+			expectedCode:  codes.NotFound,
+		},
+		{
+			name:          "transient failures run again",
+			handlerErr:    status.Error(codes.Unavailable, "unavailable"),
+			firstKey:      "key-1",
+			secondKey:     "key-1",
+			expectedCalls: 2,
+			expectedCode:  codes.Unavailable,
+		},
+		{
+			name:          "key reused for another request",
+			firstKey:      "key-1",
+			secondKey:     "key-1",
+			secondReq:     &pb.HelloRequest{Name: "Bob"},
+			expectedCalls: 1,
+			expectedErr:   ErrIdempotencyKeyReused,
+		},
+		{
+			name:          "invalid key",
+			firstKey:      "key-1",
+			secondKey:     "key with spaces",
+			expectedCalls: 1,
+			expectedErr:   ErrInvalidIdempotencyKey,
+		},
+		{
+			name:          "key too long",
+			firstKey:      "key-1",
+			secondKey:     strings.Repeat("k", 17),
+			expectedCalls: 1,
+			expectedErr:   ErrInvalidIdempotencyKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &countingHandler{err: tt.handlerErr}
+			interceptor := UnaryIdempotencyInterceptor(idempotency.NewMemoryStore(0), log, WithIdempotencyKeyMaxLength(16))
+			req := &pb.HelloRequest{Name: "Alice"}
+
+			first, firstErr := interceptor(withIdempotencyKey(tt.firstKey), req, info, h.handle)
+
+			secondReq := req
+			if tt.secondReq != nil {
+				secondReq = tt.secondReq
+			}
+
+			second, err := interceptor(withIdempotencyKey(tt.secondKey), secondReq, info, h.handle)
+
+			assert.Equal(t, tt.expectedCalls, h.calls.Load())
+
+			switch {
+			case tt.expectedErr != nil:
+				assert.ErrorIs(t, err, tt.expectedErr)
+			case tt.expectedCode != codes.OK:
+				assert.Equal(t, tt.expectedCode, status.Code(firstErr))
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+			case tt.expectedCalls == 1:
+				require.NoError(t, err)
+				assert.Equal(t, first.(*pb.HelloReply).GetSequence(), second.(*pb.HelloReply).GetSequence())
+			default:
+				require.NoError(t, err)
+				assert.NotEqual(t, first.(*pb.HelloReply).GetSequence(), second.(*pb.HelloReply).GetSequence())
+			}
+		})
+	}
+}
+
+func TestUnaryIdempotencyInterceptor_ScopedByMethod(t *testing.T) {
+	log, _ := logger.NewZapLogger("test", false)
+	h := &countingHandler{}
+	interceptor := UnaryIdempotencyInterceptor(idempotency.NewMemoryStore(0), log)
+	req := &pb.HelloRequest{Name: "Alice"}
+
+	_, err := interceptor(withIdempotencyKey("key-1"), req, &grpc.UnaryServerInfo{FullMethod: "/a"}, h.handle)
+	require.NoError(t, err)
+
+	_, err = interceptor(withIdempotencyKey("key-1"), req, &grpc.UnaryServerInfo{FullMethod: "/b"}, h.handle)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestUnaryIdempotencyInterceptor_Expiry(t *testing.T) {
+	log, _ := logger.NewZapLogger("test", false)
+	h := &countingHandler{}
+	interceptor := UnaryIdempotencyInterceptor(idempotency.NewMemoryStore(0), log, WithIdempotencyTTL(20*time.Millisecond))
+	info := &grpc.UnaryServerInfo{FullMethod: "/greeter.Greeter/SayHello"}
+	req := &pb.HelloRequest{Name: "Alice"}
+
+	_, err := interceptor(withIdempotencyKey("key-1"), req, info, h.handle)
+	require.NoError(t, err)
+
+	time.Sleep(40 * time.Millisecond)
+
+	_, err = interceptor(withIdempotencyKey("key-1"), req, info, h.handle)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(2), h.calls.Load())
+}
+
+func TestUnaryIdempotencyInterceptor_ConcurrentDuplicates(t *testing.T) {
+	log, _ := logger.NewZapLogger("test", false)
+	h := &countingHandler{release: make(chan struct{})}
+	interceptor := UnaryIdempotencyInterceptor(idempotency.NewMemoryStore(0), log)
+	info := &grpc.UnaryServerInfo{FullMethod: "/greeter.Greeter/SayHello"}
+
+	const duplicates = 5
+
+	var wg sync.WaitGroup
+
+	replies := make([]*pb.HelloReply, duplicates)
+	errs := make([]error, duplicates)
+
+	for i := range duplicates {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := interceptor(withIdempotencyKey("key-1"), &pb.HelloRequest{Name: "Alice"}, info, h.handle)
+			replies[i], _ = resp.(*pb.HelloReply)
+			errs[i] = err
+		}()
+	}
+
+	// A different request with the same key fails while the first call is in progress
+	require.Eventually(t, func() bool { return h.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	_, err := interceptor(withIdempotencyKey("key-1"), &pb.HelloRequest{Name: "Bob"}, info, h.handle)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	close(h.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), h.calls.Load())
+
+	for i := range duplicates {
+		require.NoError(t, errs[i])
+		assert.Equal(t, uint32(1), replies[i].GetSequence())
+	}
+}
+
+func TestUnaryIdempotencyInterceptor_ConcurrentTransientFailure(t *testing.T) {
+	log, _ := logger.NewZapLogger("test", false)
+	interceptor := UnaryIdempotencyInterceptor(idempotency.NewMemoryStore(0), log)
+	info := &grpc.UnaryServerInfo{FullMethod: "/greeter.Greeter/SayHello"}
+
+	var calls atomic.Int32
+
+	release := make(chan struct{})
+
+	// The first call fails transiently, the next ones succeed
+	handler := func(_ context.Context, req interface{}) (interface{}, error) {
+		n := calls.Add(1)
+		if n == 1 {
+			<-release
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+
+		return &pb.HelloReply{Message: "Hello, " + req.(*pb.HelloRequest).GetName(), Sequence: uint32(n)}, nil
+	}
+
+	leaderErr := make(chan error, 1)
+
+	go func() {
+		_, err := interceptor(withIdempotencyKey("key-1"), &pb.HelloRequest{Name: "Alice"}, info, handler)
+		leaderErr <- err
+	}()
+
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	waiter := make(chan *pb.HelloReply, 1)
+
+	go func() {
+		resp, err := interceptor(withIdempotencyKey("key-1"), &pb.HelloRequest{Name: "Alice"}, info, handler)
+		assert.NoError(t, err)
+
+		reply, _ := resp.(*pb.HelloReply)
+		waiter <- reply
+	}()
+
+	// Let the duplicate wait for the first call before it fails
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, codes.Unavailable, status.Code(<-leaderErr))
+
+	// The duplicate runs the call again rather than replay the transient failure
+	assert.Equal(t, uint32(2), (<-waiter).GetSequence())
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,263 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+	"github.com/mrityunjoydey/go-grpc/src/common/constant"
+)
+
+const (
+	// defaultPayloadMaxBytes is the default number of bytes logged per payload.
+	defaultPayloadMaxBytes = 4096
+
+	// redactedValue replaces the value of redacted string fields.
+	redactedValue = "[REDACTED]"
+)
+
+// PayloadLoggingOption configures the payload logging interceptors.
+type PayloadLoggingOption func(*payloadLoggingOptions)
+
+type payloadLoggingOptions struct {
+	methods      map[string]struct{}
+	maxBytes     int
+	redactFields map[protoreflect.Name]struct{}
+	authorizer   func(ctx context.Context) bool
+}
+
+// WithPayloadMethods enables payload logging for every call to the given full method names,
+// e.g. "/greeter.Greeter/SayHello".
+func WithPayloadMethods(methods ...string) PayloadLoggingOption {
+	return func(o *payloadLoggingOptions) {
+		for _, m := range methods {
+			o.methods[m] = struct{}{}
+		}
+	}
+}
+
+// WithPayloadMaxBytes sets the number of bytes after which a rendered payload is truncated.
+// A value of zero or less disables truncation.
+func WithPayloadMaxBytes(n int) PayloadLoggingOption {
+	return func(o *payloadLoggingOptions) {
+		o.maxBytes = n
+	}
+}
+
+// WithPayloadRedactedFields redacts the proto fields with the given names wherever they appear in a payload.
+func WithPayloadRedactedFields(fields ...string) PayloadLoggingOption {
+	return func(o *payloadLoggingOptions) {
+		for _, f := range fields {
+			o.redactFields[protoreflect.Name(f)] = struct{}{}
+		}
+	}
+}
+
+// WithDebugAuthorizer sets the function deciding whether a caller may enable payload logging
+// through the 'x-debug-log' metadata header. Without an authorizer the header is ignored.
+func WithDebugAuthorizer(fn func(ctx context.Context) bool) PayloadLoggingOption {
+	return func(o *payloadLoggingOptions) {
+		o.authorizer = fn
+	}
+}
+
+// DebugTokenAuthorizer returns an authorizer that accepts callers sending the given token in the
+// 'x-debug-token' metadata header. An empty token authorizes nobody.
+func DebugTokenAuthorizer(token string) func(ctx context.Context) bool {
+	return func(ctx context.Context) bool {
+		if token == "" {
+			return false
+		}
+
+		value := firstMetadataValue(ctx, constant.DebugTokenHeader)
+
+		return subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+	}
+}
+
+func newPayloadLoggingOptions(opts []PayloadLoggingOption) *payloadLoggingOptions {
+	o := &payloadLoggingOptions{
+		methods:      make(map[string]struct{}),
+		maxBytes:     defaultPayloadMaxBytes,
+		redactFields: make(map[protoreflect.Name]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// enabled reports whether payloads of the given call should be logged.
+func (o *payloadLoggingOptions) enabled(ctx context.Context, fullMethod string) bool {
+	if _, ok := o.methods[fullMethod]; ok {
+		return true
+	}
+
+	if o.authorizer == nil {
+		return false
+	}
+
+	flag, err := strconv.ParseBool(firstMetadataValue(ctx, constant.DebugLogHeader))
+	if err != nil || !flag {
+		return false
+	}
+
+	return o.authorizer(ctx)
+}
+
+// render returns the redacted and truncated JSON representation of a payload.
+func (o *payloadLoggingOptions) render(payload any) string {
+	var out string
+
+	if msg, ok := payload.(proto.Message); ok {
+		msg = proto.Clone(msg)
+		o.redact(msg.ProtoReflect())
+
+		bs, err := protojson.Marshal(msg)
+		if err != nil {
+			out = fmt.Sprintf("<unrenderable payload: %v>", err)
+		} else {
+			out = string(bs)
+		}
+	} else {
+		out = fmt.Sprintf("%v", payload)
+	}
+
+	if o.maxBytes > 0 && len(out) > o.maxBytes {
+		return fmt.Sprintf("%s...(truncated, %d bytes total)", out[:o.maxBytes], len(out))
+	}
+
+	return out
+}
+
+// redact walks the message and masks every populated field whose name is in the redaction list.
+func (o *payloadLoggingOptions) redact(m protoreflect.Message) {
+	if len(o.redactFields) == 0 {
+		return
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if _, ok := o.redactFields[fd.Name()]; ok {
+			if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+				m.Set(fd, protoreflect.ValueOfString(redactedValue))
+			} else {
+				m.Clear(fd)
+			}
+
+			return true
+		}
+
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				o.redact(list.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				o.redact(mv.Message())
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			o.redact(v.Message())
+		}
+
+		return true
+	})
+}
+
+// UnaryPayloadLoggingInterceptor returns a new unary server interceptor that logs request and response payloads.
+// Payloads are only logged for methods enabled through WithPayloadMethods, or when an authorized caller sends
+// the 'x-debug-log: true' metadata header.
+func UnaryPayloadLoggingInterceptor(l logger.Logger, opts ...PayloadLoggingOption) grpc.UnaryServerInterceptor {
+	o := newPayloadLoggingOptions(opts)
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if !o.enabled(ctx, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		log := l.WithContext(ctx).With(zap.String("grpc.method", info.FullMethod))
+		log.Info("request payload", zap.String("payload", o.render(req)))
+
+		resp, err := handler(ctx, req)
+		if err == nil {
+			log.Info("response payload", zap.String("payload", o.render(resp)))
+		}
+
+		return resp, err
+	}
+}
+
+// StreamPayloadLoggingInterceptor returns a new stream server interceptor that logs every message sent and
+// received on the stream. It is enabled the same way as UnaryPayloadLoggingInterceptor.
+func StreamPayloadLoggingInterceptor(l logger.Logger, opts ...PayloadLoggingOption) grpc.StreamServerInterceptor {
+	o := newPayloadLoggingOptions(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !o.enabled(ss.Context(), info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		log := l.WithContext(ss.Context()).With(zap.String("grpc.method", info.FullMethod))
+
+		return handler(srv, &payloadLoggingStream{ServerStream: ss, opts: o, logger: log})
+	}
+}
+
+// payloadLoggingStream wraps a grpc.ServerStream and logs the messages passing through it.
+type payloadLoggingStream struct {
+	grpc.ServerStream
+	opts   *payloadLoggingOptions
+	logger logger.Logger
+}
+
+func (s *payloadLoggingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.logger.Info("response payload", zap.String("payload", s.opts.render(m)))
+	}
+
+	return err
+}
+
+func (s *payloadLoggingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.logger.Info("request payload", zap.String("payload", s.opts.render(m)))
+	}
+
+	return err
+}
+
+// firstMetadataValue returns the first incoming metadata value for the given header, or an empty string.
+func firstMetadataValue(ctx context.Context, header constant.RequestHeader) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	// gRPC metadata keys are automatically lowercased
+	values := md.Get(string(header))
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+const sayHelloMethod = "/greeter.Greeter/SayHello"
+
+func TestPayloadLogging_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []PayloadLoggingOption
+		payload  any
+		expected string
+	}{
+		{
+			name:     "renders proto as json",
+			payload:  &pb.HelloRequest{Name: "World"},
+			expected: `{"name":"World"}`,
+		},
+		{
+			name:     "redacts string fields",
+			opts:     []PayloadLoggingOption{WithPayloadRedactedFields("name")},
+			payload:  &pb.HelloRequest{Name: "World"},
+			expected: `{"name":"[REDACTED]"}`,
+		},
+		{
+			name:     "renders non proto values",
+			payload:  42,
+			expected: "42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newPayloadLoggingOptions(tt.opts)
+			assert.Equal(t, tt.expected, strings.ReplaceAll(o.render(tt.payload), " ", ""))
+		})
+	}
+}
+
+func TestPayloadLogging_Truncate(t *testing.T) {
+	o := newPayloadLoggingOptions([]PayloadLoggingOption{WithPayloadMaxBytes(5)})
+
+	out := o.render(&pb.HelloRequest{Name: strings.Repeat("a", 100)})
+
+	assert.True(t, strings.HasPrefix(out, `{"nam...(truncated, `), out)
+}
+
+func TestPayloadLogging_RedactDoesNotMutatePayload(t *testing.T) {
+	o := newPayloadLoggingOptions([]PayloadLoggingOption{WithPayloadRedactedFields("name")})
+	req := &pb.HelloRequest{Name: "World"}
+
+	o.render(req)
+
+	assert.Equal(t, "World", req.GetName())
+}
+
+func TestPayloadLogging_Enabled(t *testing.T) {
+	debugCtx := func(pairs ...string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+	}
+
+	tests := []struct {
+		name     string
+		opts     []PayloadLoggingOption
+		ctx      context.Context
+		expected bool
+	}{
+		{
+			name:     "disabled by default",
+			ctx:      context.Background(),
+			expected: false,
+		},
+		{
+			name:     "enabled per method",
+			opts:     []PayloadLoggingOption{WithPayloadMethods(sayHelloMethod)},
+			ctx:      context.Background(),
+			expected: true,
+		},
+		{
+			name:     "debug header without authorizer",
+			ctx:      debugCtx("x-debug-log", "true"),
+			expected: false,
+		},
+		{
+			name:     "debug header with valid token",
+			opts:     []PayloadLoggingOption{WithDebugAuthorizer(DebugTokenAuthorizer("secret"))},
+			ctx:      debugCtx("x-debug-log", "true", "x-debug-token", "secret"),
+			expected: true,
+		},
+		{
+			name:     "debug header with invalid token",
+			opts:     []PayloadLoggingOption{WithDebugAuthorizer(DebugTokenAuthorizer("secret"))},
+			ctx:      debugCtx("x-debug-log", "true", "x-debug-token", "guess"),
+			expected: false,
+		},
+		{
+			name:     "empty token authorizes nobody",
+			opts:     []PayloadLoggingOption{WithDebugAuthorizer(DebugTokenAuthorizer(""))},
+			ctx:      debugCtx("x-debug-log", "true", "x-debug-token", ""),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newPayloadLoggingOptions(tt.opts)
+			assert.Equal(t, tt.expected, o.enabled(tt.ctx, sayHelloMethod))
+		})
+	}
+}
@@ -3,105 +3,276 @@ package middleware
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 
 	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/segmentio/ksuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/mrityunjoydey/go-grpc/pkg/logger"
 	"github.com/mrityunjoydey/go-grpc/src/common/constant"
 )
 
-// UnaryRequestIDInterceptor returns a new unary server interceptor that adds a request ID to the context.
-// It extracts the request ID from the 'x-request-id' metadata header if present, otherwise generates a new one.
-// The request ID is then set in the context for logging and tracing purposes.
-func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
-	return func(
-		ctx context.Context,
-		req interface{},
-		_ *grpc.UnaryServerInfo,
-		handler grpc.UnaryHandler,
-	) (interface{}, error) {
-		md, ok := metadata.FromIncomingContext(ctx)
-
-		var requestID string
-
-		// Try to get request ID from metadata
-		if ok {
-			// gRPC metadata keys are automatically lowercased
-			values := md.Get(string(constant.RequestIDHeader))
-			if len(values) > 0 && values[0] != "" {
-				requestID = values[0]
-			}
-		}
+// defaultRequestIDMaxLength is the default maximum length of an incoming request ID.
+const defaultRequestIDMaxLength = 128
 
-		// Generate new request ID if not found in metadata
-		if requestID == "" {
-			requestID = uuid.New().String()
-		}
+// requestIDCharset matches the characters allowed in an incoming request ID.
+var requestIDCharset = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
 
-		// Add request ID to outgoing metadata
-		mdOut := metadata.Pairs(string(constant.RequestIDHeader), requestID)
-		if err := grpc.SetHeader(ctx, mdOut); err != nil {
-			// Log error but continue with the request
-			grpclog.Errorf("Failed to set request ID in metadata: %v", err)
-		}
+// RequestIDGenerator generates a new request ID.
+type RequestIDGenerator func() string
 
-		// Add request ID to context
-		ctx = context.WithValue(ctx, logger.FieldNameRequestId, requestID)
+// UUIDv4Generator generates random UUIDv4 request IDs. This is the default generator.
+func UUIDv4Generator() string {
+	return uuid.New().String()
+}
 
-		return handler(ctx, req)
+// UUIDv7Generator generates time-ordered UUIDv7 request IDs.
+func UUIDv7Generator() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return UUIDv4Generator()
 	}
+
+	return id.String()
 }
 
-// StreamRequestIDInterceptor returns a new stream server interceptor that adds a request ID to the context.
-// It extracts the request ID from the 'x-request-id' metadata header if present, otherwise generates a new one.
-// The request ID is then set in the context for logging and tracing purposes.
-func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
-	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		ctx := ss.Context()
-		md, ok := metadata.FromIncomingContext(ctx)
-
-		var requestID string
-
-		// Try to get request ID from metadata
-		if ok {
-			// gRPC metadata keys are automatically lowercased
-			values := md.Get(string(constant.RequestIDHeader))
-			if len(values) > 0 && values[0] != "" {
-				requestID = values[0]
-			}
-		}
+// ULIDGenerator generates time-ordered ULID request IDs.
+func ULIDGenerator() string {
+	return ulid.Make().String()
+}
 
-		// Generate new request ID if not found in metadata
-		if requestID == "" {
-			requestID = uuid.New().String()
-		}
+// KSUIDGenerator generates time-ordered KSUID request IDs.
+func KSUIDGenerator() string {
+	return ksuid.New().String()
+}
+
+// RequestIDGeneratorByName returns the generator with the given name: uuidv4, uuidv7, ulid or ksuid.
+func RequestIDGeneratorByName(name string) (RequestIDGenerator, error) {
+	switch name {
+	case "", "uuidv4":
+		return UUIDv4Generator, nil
+	case "uuidv7":
+		return UUIDv7Generator, nil
+	case "ulid":
+		return ULIDGenerator, nil
+	case "ksuid":
+		return KSUIDGenerator, nil
+	default:
+		return nil, fmt.Errorf("unknown request ID generator %q", name)
+	}
+}
+
+// RequestIDOption configures the request ID interceptors.
+type RequestIDOption func(*requestIDOptions)
+
+type requestIDOptions struct {
+	generator     RequestIDGenerator
+	maxLength     int
+	rejectInvalid bool
+}
+
+// WithRequestIDGenerator sets the generator used when a call carries no valid request ID.
+func WithRequestIDGenerator(gen RequestIDGenerator) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.generator = gen
+	}
+}
+
+// WithRequestIDMaxLength sets the maximum length of an incoming request ID.
+func WithRequestIDMaxLength(n int) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.maxLength = n
+	}
+}
+
+// WithRequestIDRejectInvalid makes the interceptors fail calls carrying an invalid request ID with
+// codes.InvalidArgument, instead of replacing the ID with a generated one.
+func WithRequestIDRejectInvalid(reject bool) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.rejectInvalid = reject
+	}
+}
+
+func newRequestIDOptions(opts []RequestIDOption) *requestIDOptions {
+	o := &requestIDOptions{
+		generator: UUIDv4Generator,
+		maxLength: defaultRequestIDMaxLength,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// resolve returns the request ID of the call. It validates the ID found in the 'x-request-id' metadata header
+// and generates a new one when the header is missing or, unless invalid IDs are rejected, invalid.
+func (o *requestIDOptions) resolve(ctx context.Context) (string, error) {
+	requestID := firstMetadataValue(ctx, constant.RequestIDHeader)
+	if requestID == "" {
+		return o.generator(), nil
+	}
+
+	if len(requestID) <= o.maxLength && requestIDCharset.MatchString(requestID) {
+		return requestID, nil
+	}
+
+	if o.rejectInvalid {
+		return "", status.Errorf(codes.InvalidArgument,
+			"invalid %s: must be at most %d characters of [A-Za-z0-9._:-]", constant.RequestIDHeader, o.maxLength)
+	}
+
+	grpclog.Warningf("Replacing invalid request ID of length %d", len(requestID))
+
+	return o.generator(), nil
+}
 
-		// Add request ID to outgoing metadata
-		mdOut := metadata.Pairs(string(constant.RequestIDHeader), requestID)
-		if err := ss.SetHeader(mdOut); err != nil {
-			// Log error but continue with the request
-			grpclog.Errorf("Failed to set request ID in stream metadata: %v", err)
+// ContextWithRequestID returns a copy of ctx carrying the request ID. The ID is stored under
+// logger.FieldNameRequestId so that logger.WithContext adds it to every log entry.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, logger.FieldNameRequestId, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in the context by the request ID interceptors.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(logger.FieldNameRequestId).(string)
+	return id, ok && id != ""
+}
+
+// RequestIDEnricher is a ContextEnricher that adds a request ID to the context.
+// It extracts the request ID from the 'x-request-id' metadata header if present and valid, otherwise generates
+// a new one. The request ID is set in the context for logging and tracing, and returned to the caller in the
+// response header, the response trailer and, for failed calls, as an errdetails.RequestInfo status detail.
+type RequestIDEnricher struct {
+	opts *requestIDOptions
+}
+
+// NewRequestIDEnricher creates a new RequestIDEnricher.
+func NewRequestIDEnricher(opts ...RequestIDOption) *RequestIDEnricher {
+	return &RequestIDEnricher{opts: newRequestIDOptions(opts)}
+}
+
+// Enrich implements ContextEnricher.
+func (e *RequestIDEnricher) Enrich(ctx context.Context, call *Call) (context.Context, error) {
+	requestID, err := e.opts.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add request ID to outgoing metadata
+	mdOut := metadata.Pairs(string(constant.RequestIDHeader), requestID)
+	if err := call.SetHeader(mdOut); err != nil {
+		// Log error but continue with the request
+		grpclog.Errorf("Failed to set request ID in metadata for %s: %v", call.FullMethod, err)
+	}
+
+	return ContextWithRequestID(ctx, requestID), nil
+}
+
+// Finish implements CallFinisher.
+func (e *RequestIDEnricher) Finish(ctx context.Context, call *Call, err error) error {
+	requestID, ok := RequestIDFromContext(ctx)
+	if !ok {
+		return err
+	}
+
+	// Trailers reach the caller even when the handler fails before headers are sent
+	mdOut := metadata.Pairs(string(constant.RequestIDHeader), requestID)
+	if terr := call.SetTrailer(mdOut); terr != nil {
+		grpclog.Errorf("Failed to set request ID in trailer for %s: %v", call.FullMethod, terr)
+	}
+
+	return withRequestInfo(err, requestID)
+}
+
+// withRequestInfo attaches the request ID to a non-OK status as an errdetails.RequestInfo detail.
+func withRequestInfo(err error, requestID string) error {
+	if err == nil {
+		return nil
+	}
+
+	st := status.Convert(err)
+	if st.Code() == codes.OK {
+		return err
+	}
+
+	for _, d := range st.Details() {
+		if _, ok := d.(*errdetails.RequestInfo); ok {
+			return err
 		}
+	}
 
-		// Create new context with request ID
-		newCtx := context.WithValue(ctx, logger.FieldNameRequestId, requestID)
+	withDetails, derr := st.WithDetails(&errdetails.RequestInfo{RequestId: requestID})
+	if derr != nil {
+		grpclog.Errorf("Failed to attach request ID to status: %v", derr)
+		return err
+	}
 
-		// Wrap the server stream with our context
-		wrapped := &wrappedStream{ServerStream: ss, newCtx: newCtx}
+	return withDetails.Err()
+}
 
-		return handler(srv, wrapped)
+// UnaryRequestIDInterceptor returns a new unary server interceptor that adds a request ID to the context.
+// See RequestIDEnricher for details.
+func UnaryRequestIDInterceptor(opts ...RequestIDOption) grpc.UnaryServerInterceptor {
+	return UnaryEnricherInterceptor(NewRequestIDEnricher(opts...))
+}
+
+// StreamRequestIDInterceptor returns a new stream server interceptor that adds a request ID to the context.
+// See RequestIDEnricher for details.
+func StreamRequestIDInterceptor(opts ...RequestIDOption) grpc.StreamServerInterceptor {
+	return StreamEnricherInterceptor(NewRequestIDEnricher(opts...))
+}
+
+// UnaryClientRequestIDInterceptor returns a new unary client interceptor that copies the request ID from the
+// context to the outgoing 'x-request-id' metadata header, so chained service calls keep the same ID.
+// A request ID already present in the outgoing metadata is left untouched.
+func UnaryClientRequestIDInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(outgoingRequestIDContext(ctx), method, req, reply, cc, opts...)
 	}
 }
 
-// wrappedStream wraps a grpc.ServerStream and overrides its context.
-type wrappedStream struct {
-	grpc.ServerStream
-	newCtx context.Context
+// StreamClientRequestIDInterceptor returns a new stream client interceptor that copies the request ID from the
+// context to the outgoing 'x-request-id' metadata header, so chained service calls keep the same ID.
+func StreamClientRequestIDInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(outgoingRequestIDContext(ctx), desc, cc, method, opts...)
+	}
 }
 
-func (w *wrappedStream) Context() context.Context {
-	return w.newCtx
+// outgoingRequestIDContext adds the request ID from the context to the outgoing metadata.
+func outgoingRequestIDContext(ctx context.Context) context.Context {
+	requestID, ok := RequestIDFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(string(constant.RequestIDHeader))) > 0 {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, string(constant.RequestIDHeader), requestID)
 }
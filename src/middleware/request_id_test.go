@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/segmentio/ksuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+)
+
+func incomingRequestIDContext(id string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", id))
+}
+
+func TestRequestID_Resolve(t *testing.T) {
+	fixed := func() string { return "generated" }
+
+	tests := []struct {
+		name     string
+		opts     []RequestIDOption
+		ctx      context.Context
+		expected string
+		code     codes.Code
+	}{
+		{
+			name:     "generates when missing",
+			ctx:      context.Background(),
+			expected: "generated",
+		},
+		{
+			name:     "keeps valid id",
+			ctx:      incomingRequestIDContext("client-generated-id-123"),
+			expected: "client-generated-id-123",
+		},
+		{
+			name:     "replaces id with invalid characters",
+			ctx:      incomingRequestIDContext("bad id\n"),
+			expected: "generated",
+		},
+		{
+			name:     "replaces too long id",
+			opts:     []RequestIDOption{WithRequestIDMaxLength(4)},
+			ctx:      incomingRequestIDContext("12345"),
+			expected: "generated",
+		},
+		{
+			name: "rejects invalid id",
+			opts: []RequestIDOption{WithRequestIDRejectInvalid(true)},
+			ctx:  incomingRequestIDContext(strings.Repeat("a", 200)),
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newRequestIDOptions(append([]RequestIDOption{WithRequestIDGenerator(fixed)}, tt.opts...))
+
+			id, err := o.resolve(tt.ctx)
+			if tt.code != codes.OK {
+				assert.Equal(t, tt.code, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, id)
+		})
+	}
+}
+
+func TestRequestIDGeneratorByName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid func(id string) bool
+	}{
+		{name: "uuidv4", valid: func(id string) bool { return uuid.MustParse(id).Version() == 4 }},
+		{name: "uuidv7", valid: func(id string) bool { return uuid.MustParse(id).Version() == 7 }},
+		{name: "ulid", valid: func(id string) bool { _, err := ulid.Parse(id); return err == nil }},
+		{name: "ksuid", valid: func(id string) bool { _, err := ksuid.Parse(id); return err == nil }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := RequestIDGeneratorByName(tt.name)
+			require.NoError(t, err)
+
+			id := gen()
+			assert.True(t, tt.valid(id), id)
+			assert.True(t, requestIDCharset.MatchString(id), id)
+		})
+	}
+
+	_, err := RequestIDGeneratorByName("snowflake")
+	assert.Error(t, err)
+}
+
+func TestUnaryClientRequestIDInterceptor(t *testing.T) {
+	interceptor := UnaryClientRequestIDInterceptor()
+
+	outgoingID := func(ctx context.Context) []string {
+		var got []string
+
+		invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			got = md.Get("x-request-id")
+
+			return nil
+		}
+
+		require.NoError(t, interceptor(ctx, "/greeter.Greeter/SayHello", nil, nil, nil, invoker))
+
+		return got
+	}
+
+	t.Run("propagates request id", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), logger.FieldNameRequestId, "abc-123")
+		assert.Equal(t, []string{"abc-123"}, outgoingID(ctx))
+	})
+
+	t.Run("keeps explicit outgoing id", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), logger.FieldNameRequestId, "abc-123")
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "explicit")
+		assert.Equal(t, []string{"explicit"}, outgoingID(ctx))
+	})
+
+	t.Run("without request id", func(t *testing.T) {
+		assert.Empty(t, outgoingID(context.Background()))
+	})
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	validator "github.com/go-playground/validator/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+	"github.com/mrityunjoydey/go-grpc/rpc/validate"
+)
+
+// ReasonValidationFailed is the ErrorInfo reason of requests rejected by the validation interceptors.
+const ReasonValidationFailed = "VALIDATION_FAILED"
+
+// fieldRule is the compiled (validate.field) option of a single field.
+type fieldRule struct {
+	field   protoreflect.FieldDescriptor
+	rules   string
+	pattern *regexp.Regexp
+}
+
+// messageValidator validates proto messages against the (validate.field) options of their fields.
+// The rules of every message type are read once and cached.
+type messageValidator struct {
+	validate *validator.Validate
+	rules    sync.Map // protoreflect.FullName -> []fieldRule
+}
+
+func newMessageValidator() *messageValidator {
+	return &messageValidator{validate: validator.New()}
+}
+
+// Validate checks the message and returns a codes.InvalidArgument error listing every field violation.
+func (v *messageValidator) Validate(msg proto.Message) error {
+	violations := v.check(msg.ProtoReflect(), "")
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return grpcerr.InvalidArgument(ReasonValidationFailed, "request validation failed", violations...)
+}
+
+func (v *messageValidator) check(m protoreflect.Message, prefix string) []grpcerr.FieldViolation {
+	var violations []grpcerr.FieldViolation
+
+	for _, r := range v.rulesFor(m.Descriptor()) {
+		path := prefix + string(r.field.Name())
+		value := m.Get(r.field)
+
+		if r.field.IsList() {
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				violations = append(violations, v.checkValue(r, fmt.Sprintf("%s[%d]", path, i), list.Get(i))...)
+			}
+
+			continue
+		}
+
+		violations = append(violations, v.checkValue(r, path, value)...)
+	}
+
+	// Nested messages are validated against their own rules
+	m.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if fd.Message() == nil || fd.IsMap() {
+			return true
+		}
+
+		path := prefix + string(fd.Name())
+
+		if fd.IsList() {
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				violations = append(violations, v.check(list.Get(i).Message(), fmt.Sprintf("%s[%d].", path, i))...)
+			}
+
+			return true
+		}
+
+		violations = append(violations, v.check(value.Message(), path+".")...)
+
+		return true
+	})
+
+	return violations
+}
+
+func (v *messageValidator) checkValue(r fieldRule, path string, value protoreflect.Value) []grpcerr.FieldViolation {
+	var violations []grpcerr.FieldViolation
+
+	if r.rules != "" {
+		var verrs validator.ValidationErrors
+		if err := v.validate.Var(value.Interface(), r.rules); errors.As(err, &verrs) {
+			for _, fe := range verrs {
+				violations = append(violations, grpcerr.FieldViolation{Field: path, Description: describeRule(fe)})
+			}
+		} else if err != nil {
+			violations = append(violations, grpcerr.FieldViolation{Field: path, Description: err.Error()})
+		}
+	}
+
+	if s, ok := value.Interface().(string); ok && r.pattern != nil && s != "" && !r.pattern.MatchString(s) {
+		violations = append(violations, grpcerr.FieldViolation{
+			Field:       path,
+			Description: fmt.Sprintf("must match the pattern %q", r.pattern.String()),
+		})
+	}
+
+	return violations
+}
+
+// describeRule renders a validator error the way the config package reports it, e.g. "failed on the 'max=64' rule".
+func describeRule(fe validator.FieldError) string {
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed on the '%s=%s' rule", fe.Tag(), fe.Param())
+	}
+
+	return fmt.Sprintf("failed on the '%s' rule", fe.Tag())
+}
+
+// rulesFor returns the cached rules of the message type, reading them from its field options on first use.
+func (v *messageValidator) rulesFor(md protoreflect.MessageDescriptor) []fieldRule {
+	if cached, ok := v.rules.Load(md.FullName()); ok {
+		return cached.([]fieldRule)
+	}
+
+	var rules []fieldRule
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+
+		opts := fd.Options()
+		if opts == nil || !proto.HasExtension(opts, validate.E_Field) {
+			continue
+		}
+
+		fr, ok := proto.GetExtension(opts, validate.E_Field).(*validate.FieldRules)
+		if !ok {
+			continue
+		}
+
+		r := fieldRule{field: fd, rules: fr.GetRules()}
+		if fr.GetPattern() != "" {
+			// Invalid patterns are programming errors in the proto definitions
+			r.pattern = regexp.MustCompile(fr.GetPattern())
+		}
+
+		rules = append(rules, r)
+	}
+
+	v.rules.Store(md.FullName(), rules)
+
+	return rules
+}
+
+// UnaryValidationInterceptor returns a new unary server interceptor that validates every request against the
+// (validate.field) options declared in the proto definitions. Invalid requests fail with codes.InvalidArgument
+// and a BadRequest detail listing every field violation.
+func UnaryValidationInterceptor() grpc.UnaryServerInterceptor {
+	v := newMessageValidator()
+
+	return func(
+		ctx context.Context,
+		req interface{},
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if msg, ok := req.(proto.Message); ok {
+			if err := v.Validate(msg); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamValidationInterceptor returns a new stream server interceptor that validates every message received
+// on the stream. An invalid message is returned to the handler as an error from RecvMsg.
+func StreamValidationInterceptor() grpc.StreamServerInterceptor {
+	v := newMessageValidator()
+
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingStream{ServerStream: ss, validator: v})
+	}
+}
+
+// validatingStream wraps a grpc.ServerStream and validates every received message.
+type validatingStream struct {
+	grpc.ServerStream
+	validator *messageValidator
+}
+
+func (s *validatingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if msg, ok := m.(proto.Message); ok {
+		return s.validator.Validate(msg)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+func TestMessageValidator_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *pb.HelloRequest
+		description string
+	}{
+		{name: "valid", req: &pb.HelloRequest{Name: "Zoë O'Neil"}},
+		{name: "empty", req: &pb.HelloRequest{}, description: "failed on the 'required' rule"},
+		{name: "too long", req: &pb.HelloRequest{Name: strings.Repeat("a", 65)}, description: "failed on the 'max=64' rule"},
+		{name: "invalid characters", req: &pb.HelloRequest{Name: "<script>"}, description: "must match the pattern"},
+	}
+
+	v := newMessageValidator()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.req)
+			if tt.description == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			st := status.Convert(err)
+			assert.Equal(t, codes.InvalidArgument, st.Code())
+
+			var badRequest *errdetails.BadRequest
+
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					badRequest = br
+				}
+			}
+
+			require.NotNil(t, badRequest)
+			require.NotEmpty(t, badRequest.GetFieldViolations())
+			assert.Equal(t, "name", badRequest.GetFieldViolations()[0].GetField())
+			assert.Contains(t, badRequest.GetFieldViolations()[0].GetDescription(), tt.description)
+		})
+	}
+}
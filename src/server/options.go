@@ -0,0 +1,95 @@
+package server
+
+import (
+	"github.com/mrityunjoydey/go-grpc/pkg/audit"
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+	"github.com/mrityunjoydey/go-grpc/pkg/idempotency"
+	"github.com/mrityunjoydey/go-grpc/src/middleware"
+	"github.com/mrityunjoydey/go-grpc/src/service/greeter"
+)
+
+// Option configures the Server.
+type Option func(*options)
+
+type options struct {
+	payloadLogging []middleware.PayloadLoggingOption
+	auditLogger    *audit.Logger
+	requestID      []middleware.RequestIDOption
+	deadline       []middleware.DeadlineOption
+	greeter        []greeter.Option
+
+	idempotencyStore idempotency.Store
+	idempotency      []middleware.IdempotencyOption
+	cache            []middleware.CacheOption
+
+	flags      *flags.Set
+	adminToken string
+}
+
+// WithRequestID configures the request ID interceptors.
+func WithRequestID(opts ...middleware.RequestIDOption) Option {
+	return func(o *options) {
+		o.requestID = append(o.requestID, opts...)
+	}
+}
+
+// WithDeadlines configures the deadline interceptors.
+func WithDeadlines(opts ...middleware.DeadlineOption) Option {
+	return func(o *options) {
+		o.deadline = append(o.deadline, opts...)
+	}
+}
+
+// WithPayloadLogging configures the payload logging interceptors.
+func WithPayloadLogging(opts ...middleware.PayloadLoggingOption) Option {
+	return func(o *options) {
+		o.payloadLogging = append(o.payloadLogging, opts...)
+	}
+}
+
+// WithAuditLogger enables the audit interceptors, writing one record per call to the given audit logger.
+func WithAuditLogger(a *audit.Logger) Option {
+	return func(o *options) {
+		o.auditLogger = a
+	}
+}
+
+// WithIdempotency enables the idempotency interceptor, recording the outcome of calls made with an idempotency
+// key in the given store.
+func WithIdempotency(store idempotency.Store, opts ...middleware.IdempotencyOption) Option {
+	return func(o *options) {
+		o.idempotencyStore = store
+		o.idempotency = append(o.idempotency, opts...)
+	}
+}
+
+// WithCache configures the cache interceptor. Only the methods given a policy with middleware.WithCachedMethod
+// are cached.
+func WithCache(opts ...middleware.CacheOption) Option {
+	return func(o *options) {
+		o.cache = append(o.cache, opts...)
+	}
+}
+
+// WithGreeterOptions configures the Greeter service.
+func WithGreeterOptions(opts ...greeter.Option) Option {
+	return func(o *options) {
+		o.greeter = append(o.greeter, opts...)
+	}
+}
+
+// WithFlags adds the attributes feature flags are evaluated against to the context of every call, and gives the
+// set to the Greeter service, whose handlers evaluate its flags. The set is listed by the Admin service.
+func WithFlags(set *flags.Set) Option {
+	return func(o *options) {
+		o.flags = set
+	}
+}
+
+// WithAdmin registers the Admin service, authorizing callers sending the given token in the 'x-admin-token'
+// metadata header. The service is not registered when the token is empty.
+func WithAdmin(token string) Option {
+	return func(o *options) {
+		o.adminToken = token
+	}
+}
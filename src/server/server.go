@@ -11,16 +11,17 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
-	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/reflection"
 
-	"github.com/mrityunjoydey/go-grpc/src/middleware"
-	"github.com/mrityunjoydey/go-grpc/src/service/greeter"
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
 	"github.com/mrityunjoydey/go-grpc/pkg/logger"
 	pb "github.com/mrityunjoydey/go-grpc/rpc"
+	adminpb "github.com/mrityunjoydey/go-grpc/rpc/admin"
+	"github.com/mrityunjoydey/go-grpc/src/middleware"
+	"github.com/mrityunjoydey/go-grpc/src/service/admin"
+	"github.com/mrityunjoydey/go-grpc/src/service/greeter"
 )
 
 // Server is the gRPC server.
@@ -29,6 +30,7 @@ type Server struct {
 	grpcServer *grpc.Server
 	port       string
 	healthSrv  *health.Server
+	greeter    *greeter.Service
 }
 
 // interceptorLogger adapts zap logger to the interceptor's logger interface.
@@ -70,7 +72,12 @@ func interceptorLogger(l logger.Logger) logging.Logger {
 }
 
 // New creates a new gRPC server.
-func New(port string, logger logger.Logger) *Server {
+func New(port string, logger logger.Logger, opts ...Option) *Server {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	// Setup panic recovery handler
 	recoveryOpts := []recovery.Option{
 		recovery.WithRecoveryHandlerContext(func(ctx context.Context, p any) (err error) {
@@ -78,28 +85,81 @@ func New(port string, logger logger.Logger) *Server {
 				zap.Any("panic", p),
 				zap.String("stack", string(debug.Stack())),
 			)
-			return status.Errorf(codes.Internal, "internal server error")
+			return grpcerr.Internal("internal server error")
 		}),
 	}
 
-	// Create a new gRPC server with unary interceptors
+	// The deadline interceptors run before logging so that the deadline budget appears in the log fields
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		middleware.UnaryRequestIDInterceptor(o.requestID...),
+		middleware.UnaryDeadlineInterceptor(o.deadline...),
+		logging.UnaryServerInterceptor(interceptorLogger(logger)),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		middleware.StreamRequestIDInterceptor(o.requestID...),
+		middleware.StreamDeadlineInterceptor(o.deadline...),
+		logging.StreamServerInterceptor(interceptorLogger(logger)),
+	}
+
+	// The flag attributes include the request ID, so they are added after it
+	if o.flags != nil {
+		unaryInterceptors = append(unaryInterceptors, middleware.UnaryFlagsInterceptor())
+		streamInterceptors = append(streamInterceptors, middleware.StreamFlagsInterceptor())
+	}
+
+	// The audit interceptors run outside recovery so that panics are recorded with their final status code
+	if o.auditLogger != nil {
+		unaryInterceptors = append(unaryInterceptors, middleware.UnaryAuditInterceptor(o.auditLogger, logger))
+		streamInterceptors = append(streamInterceptors, middleware.StreamAuditInterceptor(o.auditLogger, logger))
+	}
+
+	// The error interceptors run inside logging and audit so that both record the converted status
+	unaryInterceptors = append(unaryInterceptors,
+		grpcerr.UnaryServerInterceptor(logger),
+		recovery.UnaryServerInterceptor(recoveryOpts...),
+		middleware.UnaryPayloadLoggingInterceptor(logger, o.payloadLogging...),
+		// Invalid requests are rejected before idempotency and the cache, so that they are never recorded,
+		// replayed or coalesced
+		middleware.UnaryValidationInterceptor(),
+	)
+
+	// The idempotency interceptor runs inside recovery so that a panicking call is not recorded
+	if o.idempotencyStore != nil {
+		unaryInterceptors = append(unaryInterceptors,
+			middleware.UnaryIdempotencyInterceptor(o.idempotencyStore, logger, o.idempotency...))
+	}
+
+	// The cache interceptor runs after idempotency so that a replayed call never reaches the cache
+	unaryInterceptors = append(unaryInterceptors,
+		middleware.UnaryCacheInterceptor(o.cache...),
+	)
+	streamInterceptors = append(streamInterceptors,
+		grpcerr.StreamServerInterceptor(logger),
+		recovery.StreamServerInterceptor(recoveryOpts...),
+		middleware.StreamPayloadLoggingInterceptor(logger, o.payloadLogging...),
+		middleware.StreamValidationInterceptor(),
+	)
+
+	// Create a new gRPC server with unary and stream interceptors
 	gs := grpc.NewServer(
-		grpc.ChainUnaryInterceptor(
-			middleware.UnaryRequestIDInterceptor(),
-			logging.UnaryServerInterceptor(interceptorLogger(logger)),
-			recovery.UnaryServerInterceptor(recoveryOpts...),
-		),
-		grpc.ChainStreamInterceptor(
-			middleware.StreamRequestIDInterceptor(),
-			logging.StreamServerInterceptor(interceptorLogger(logger)),
-			recovery.StreamServerInterceptor(recoveryOpts...),
-		),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
 	)
 
-	// Register Greeter service
-	greeterService := greeter.NewService(logger)
+	// Register Greeter service, gating its greetings with the feature flags
+	greeterOpts := o.greeter
+	if o.flags != nil {
+		greeterOpts = append([]greeter.Option{greeter.WithFlags(o.flags)}, greeterOpts...)
+	}
+
+	greeterService := greeter.NewService(logger, greeterOpts...)
 	pb.RegisterGreeterServer(gs, greeterService)
 
+	// Register the Admin service when operators have a token to call it
+	if o.adminToken != "" {
+		adminpb.RegisterAdminServer(gs, admin.NewService(o.adminToken, o.flags))
+	}
+
 	// Register health check service
 	healthSrv := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(gs, healthSrv)
@@ -113,9 +173,15 @@ func New(port string, logger logger.Logger) *Server {
 		grpcServer: gs,
 		port:       port,
 		healthSrv:  healthSrv,
+		greeter:    greeterService,
 	}
 }
 
+// Greeter returns the Greeter service, e.g. to apply reloaded settings.
+func (s *Server) Greeter() *greeter.Service {
+	return s.greeter
+}
+
 // Serve starts the gRPC server on the given listener. This is useful for testing with a bufconn listener.
 func (s *Server) serve(lis net.Listener) error {
 	s.logger.Info("gRPC server starting to serve")
@@ -148,5 +214,7 @@ func (s *Server) Stop() {
 	s.logger.Info("Stopping gRPC server")
 	// Set the health status to NOT_SERVING
 	s.healthSrv.SetServingStatus(pb.Greeter_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	// End the Chat sessions, which would otherwise keep the graceful stop waiting
+	s.greeter.Close()
 	s.grpcServer.GracefulStop()
 }
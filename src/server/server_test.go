@@ -2,18 +2,27 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+	"github.com/mrityunjoydey/go-grpc/pkg/idempotency"
 	"github.com/mrityunjoydey/go-grpc/pkg/logger"
 	pb "github.com/mrityunjoydey/go-grpc/rpc"
+	"github.com/mrityunjoydey/go-grpc/src/middleware"
+	"github.com/mrityunjoydey/go-grpc/src/service/greeter"
+	"github.com/mrityunjoydey/go-grpc/src/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/resolver"
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
@@ -95,3 +104,274 @@ func TestServer_StartStop(t *testing.T) {
 	require.True(t, ok, "error should be a gRPC status error")
 	assert.Equal(t, codes.Unavailable, st.Code(), "expected status code to be Unavailable")
 }
+
+// startTestServer serves a new Server on a bufconn listener and returns a connection to it.
+func startTestServer(t *testing.T, opts ...Option) *grpc.ClientConn {
+	t.Helper()
+
+	logger, err := logger.NewZapLogger("test", false)
+	require.NoError(t, err)
+
+	bufListener := newBufconnListener()
+	srv := New("", logger, opts...)
+
+	go func() {
+		if err := srv.serve(bufListener); err != nil && err != grpc.ErrServerStopped {
+			t.Logf("server error: %v", err)
+		}
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return bufListener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		if err := conn.Close(); err != nil {
+			t.Logf("failed to close connection: %v", err)
+		}
+
+		srv.Stop()
+	})
+
+	return conn
+}
+
+func TestServer_ErrorDetails(t *testing.T) {
+	conn := startTestServer(t)
+	client := pb.NewGreeterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "error-details-test")
+
+	var trailer metadata.MD
+
+	_, err := client.SayHello(ctx, &pb.HelloRequest{}, grpc.Trailer(&trailer))
+	require.Error(t, err)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, []string{"error-details-test"}, trailer.Get("x-request-id"))
+
+	var (
+		info        *errdetails.ErrorInfo
+		requestInfo *errdetails.RequestInfo
+	)
+
+	for _, d := range st.Details() {
+		switch v := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = v
+		case *errdetails.RequestInfo:
+			requestInfo = v
+		}
+	}
+
+	require.NotNil(t, info)
+	assert.Equal(t, middleware.ReasonValidationFailed, info.GetReason())
+	require.NotNil(t, requestInfo)
+	assert.Equal(t, "error-details-test", requestInfo.GetRequestId())
+}
+
+func TestServer_Idempotency(t *testing.T) {
+	conn := startTestServer(t,
+		WithIdempotency(idempotency.NewMemoryStore(0)),
+		WithGreeterOptions(greeter.WithStore(store.NewMemoryStore(0))),
+	)
+	client := pb.NewGreeterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "idempotency-key", "retry-1")
+
+	var first, second metadata.MD
+
+	_, err := client.SayHello(ctx, &pb.HelloRequest{Name: "Alice"}, grpc.Header(&first))
+	require.NoError(t, err)
+
+	reply, err := client.SayHello(ctx, &pb.HelloRequest{Name: "Alice"}, grpc.Header(&second))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Alice", reply.GetMessage())
+
+	assert.Empty(t, first.Get("idempotent-replayed"))
+	assert.Equal(t, []string{"true"}, second.Get("idempotent-replayed"))
+
+	// The retried greeting is recorded once
+	res, err := client.ListGreetings(ctx, &pb.ListGreetingsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, res.GetGreetings(), 1)
+
+	// Invalid requests are rejected before their key is used
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs("idempotency-key", "invalid-1"))
+
+	_, err = client.SayHello(ctx, &pb.HelloRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	reply, err = client.SayHello(ctx, &pb.HelloRequest{Name: "Bob"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Bob", reply.GetMessage())
+}
+
+func TestServer_Cache(t *testing.T) {
+	conn := startTestServer(t,
+		WithCache(middleware.WithCachedMethod("/greeter.Greeter/ListGreetings", middleware.CachePolicy{TTL: time.Minute})),
+		WithGreeterOptions(greeter.WithStore(store.NewMemoryStore(0))),
+	)
+	client := pb.NewGreeterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var miss, hit metadata.MD
+
+	_, err := client.ListGreetings(ctx, &pb.ListGreetingsRequest{}, grpc.Header(&miss))
+	require.NoError(t, err)
+
+	_, err = client.SayHello(ctx, &pb.HelloRequest{Name: "Alice"})
+	require.NoError(t, err)
+
+	// The cached response does not list the new greeting yet
+	res, err := client.ListGreetings(ctx, &pb.ListGreetingsRequest{}, grpc.Header(&hit))
+	require.NoError(t, err)
+	assert.Empty(t, res.GetGreetings())
+
+	assert.Equal(t, []string{"MISS"}, miss.Get("x-cache"))
+	assert.Equal(t, []string{"HIT"}, hit.Get("x-cache"))
+	assert.Equal(t, []string{"0"}, hit.Get("age"))
+
+	res, err = client.ListGreetings(metadata.AppendToOutgoingContext(ctx, "cache-control", "no-cache"),
+		&pb.ListGreetingsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, res.GetGreetings(), 1)
+}
+
+func TestServer_StreamDeadlines(t *testing.T) {
+	conn := startTestServer(t, WithDeadlines(middleware.WithDefaultDeadlinePolicy(middleware.DeadlinePolicy{
+		Default:    50 * time.Millisecond,
+		StreamIdle: 200 * time.Millisecond,
+	})))
+	client := pb.NewGreeterClient(conn)
+
+	// No client deadline: the stream is only bounded by its idle timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Chat(ctx)
+	require.NoError(t, err)
+
+	for _, name := range []string{"Dave", "Eve"} {
+		require.NoError(t, stream.Send(&pb.HelloRequest{Name: name}))
+
+		// Outlive the default timeout between messages
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	replies := 0
+
+	for {
+		_, err := stream.Recv()
+		if err != nil {
+			st := status.Convert(err)
+			assert.Equal(t, codes.DeadlineExceeded, st.Code())
+			assert.Equal(t, "stream idle timeout exceeded", st.Message())
+
+			break
+		}
+
+		replies++
+	}
+
+	// The join event and both greetings
+	assert.Equal(t, 3, replies)
+}
+
+func TestServer_Flags(t *testing.T) {
+	emoji := flags.Flag{Name: greeter.EmojiFlag, Kind: flags.Percentage, Enabled: true, Percentage: 50}
+	set := flags.NewSet([]flags.Flag{emoji})
+
+	conn := startTestServer(t, WithFlags(set))
+	client := pb.NewGreeterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sayHello := func(requestID string) string {
+		res, err := client.SayHello(metadata.AppendToOutgoingContext(ctx, "x-request-id", requestID),
+			&pb.HelloRequest{Name: "World"})
+		require.NoError(t, err)
+
+		return res.GetMessage()
+	}
+
+	// Anonymous callers are bucketed by request ID: half of the requests get the emoji
+	on := 0
+
+	for i := range 40 {
+		requestID := fmt.Sprintf("flags-%d", i)
+		a := flags.Attributes{Method: "/greeter.Greeter/SayHello", RequestID: requestID}
+
+		want := "Hello, World"
+		if emoji.Evaluate(a).On {
+			want += " 👋"
+			on++
+		}
+
+		assert.Equal(t, want, sayHello(requestID))
+	}
+
+	assert.Positive(t, on)
+	assert.Less(t, on, 40)
+
+	// Updates apply to the next calls
+	set.Update([]flags.Flag{{Name: greeter.EmojiFlag, Kind: flags.Bool, Enabled: true}})
+	assert.Equal(t, "Hello, World 👋", sayHello("flags-update"))
+
+	set.Update(nil)
+	assert.Equal(t, "Hello, World", sayHello("flags-update"))
+}
+
+func TestServer_StreamGreetingsResume(t *testing.T) {
+	conn := startTestServer(t)
+	client := pb.NewGreeterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.StreamGreetings(ctx, &pb.HelloRequest{Name: "Streamer", Count: 4})
+	require.NoError(t, err)
+
+	// Interrupt the stream after the second greeting
+	var token string
+
+	for range 2 {
+		reply, err := stream.Recv()
+		require.NoError(t, err)
+
+		token = reply.GetResumeToken()
+	}
+
+	// The token issued by the server passes request validation and resumes after the second greeting
+	stream, err = client.StreamGreetings(ctx, &pb.HelloRequest{Name: "Streamer", Count: 4, ResumeToken: token})
+	require.NoError(t, err)
+
+	var sequences []uint32
+
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		sequences = append(sequences, reply.GetSequence())
+	}
+
+	assert.Equal(t, []uint32{3, 4}, sequences)
+}
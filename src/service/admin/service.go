@@ -0,0 +1,89 @@
+// Package admin implements the AdminServer interface, exposing the runtime state of the server to operators.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"math"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+	pb "github.com/mrityunjoydey/go-grpc/rpc/admin"
+	"github.com/mrityunjoydey/go-grpc/src/common/constant"
+)
+
+// ErrUnauthenticated is returned to callers without the admin token.
+var ErrUnauthenticated = grpcerr.New(codes.Unauthenticated, "ADMIN_TOKEN_INVALID", "a valid admin token is required")
+
+// Service implements the AdminServer interface.
+type Service struct {
+	pb.UnimplementedAdminServer
+	token string
+	flags *flags.Set
+}
+
+// NewService creates the Admin service. Callers must send the token in the 'x-admin-token' metadata header; an
+// empty token authorizes nobody.
+func NewService(token string, set *flags.Set) *Service {
+	return &Service{token: token, flags: set}
+}
+
+// ListFlags lists the current feature flags, evaluated for the request described by req when it sets a field.
+func (s *Service) ListFlags(ctx context.Context, req *pb.ListFlagsRequest) (*pb.ListFlagsResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	if s.flags == nil {
+		return &pb.ListFlagsResponse{}, nil
+	}
+
+	a := flags.Attributes{Method: req.GetMethod(), Principal: req.GetPrincipal(), RequestID: req.GetRequestId()}
+	evaluate := a != flags.Attributes{}
+
+	resp := &pb.ListFlagsResponse{}
+
+	for _, f := range s.flags.Flags() {
+		flag := &pb.Flag{
+			Name:       f.Name,
+			Kind:       string(f.Kind),
+			Enabled:    f.Enabled,
+			Percentage: f.Percentage,
+			Methods:    f.Methods,
+			Principals: f.Principals,
+		}
+
+		for _, v := range f.Variants {
+			weight := int32(min(v.Weight, math.MaxInt32)) //nolint:gosec // clamped to the range of int32
+			flag.Variants = append(flag.Variants, &pb.Variant{Name: v.Name, Weight: weight})
+		}
+
+		if evaluate {
+			e := f.Evaluate(a)
+			flag.Evaluation = &pb.Evaluation{On: e.On, Variant: e.Variant, Reason: string(e.Reason)}
+		}
+
+		resp.Flags = append(resp.Flags, flag)
+	}
+
+	return resp, nil
+}
+
+// authorize checks the admin token of the caller.
+func (s *Service) authorize(ctx context.Context) error {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(string(constant.AdminTokenHeader)); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		return ErrUnauthenticated
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+	pb "github.com/mrityunjoydey/go-grpc/rpc/admin"
+)
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-admin-token", token))
+}
+
+func TestService_ListFlags_Unauthenticated(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *Service
+		ctx     context.Context
+	}{
+		{name: "missing token", service: NewService("s3cr3t", nil), ctx: context.Background()},
+		{name: "wrong token", service: NewService("s3cr3t", nil), ctx: withToken("guess")},
+		{name: "no configured token", service: NewService("", nil), ctx: withToken("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.service.ListFlags(tt.ctx, &pb.ListFlagsRequest{})
+			assert.ErrorIs(t, err, ErrUnauthenticated)
+		})
+	}
+}
+
+func TestService_ListFlags(t *testing.T) {
+	set := flags.NewSet([]flags.Flag{
+		{Name: "greeter.style", Kind: flags.Variant, Enabled: true, Principals: []string{"alice"},
+			Variants: []flags.WeightedVariant{{Name: "casual", Weight: 1}, {Name: "formal", Weight: 1}}},
+		{Name: "greeter.emoji", Kind: flags.Bool, Methods: []string{"/greeter.Greeter/SayHello"}},
+	})
+	svc := NewService("s3cr3t", set)
+
+	resp, err := svc.ListFlags(withToken("s3cr3t"), &pb.ListFlagsRequest{})
+	require.NoError(t, err)
+
+	want := &pb.ListFlagsResponse{Flags: []*pb.Flag{
+		{Name: "greeter.emoji", Kind: "bool", Methods: []string{"/greeter.Greeter/SayHello"}},
+		{Name: "greeter.style", Kind: "variant", Enabled: true, Principals: []string{"alice"},
+			Variants: []*pb.Variant{{Name: "casual", Weight: 1}, {Name: "formal", Weight: 1}}},
+	}}
+	assert.True(t, proto.Equal(want, resp), "got %v", resp)
+
+	// The flags are evaluated for the request described
+	resp, err = svc.ListFlags(withToken("s3cr3t"), &pb.ListFlagsRequest{Principal: "alice"})
+	require.NoError(t, err)
+
+	assert.True(t, proto.Equal(&pb.Evaluation{Reason: "disabled"}, resp.Flags[0].Evaluation))
+	assert.True(t, proto.Equal(&pb.Evaluation{On: true, Variant: "casual", Reason: "principal"}, resp.Flags[1].Evaluation))
+}
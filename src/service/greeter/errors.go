@@ -0,0 +1,61 @@
+package greeter
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+)
+
+// ErrNameRequired is returned when a request carries no name to greet.
+var ErrNameRequired = grpcerr.InvalidArgument("NAME_REQUIRED", "name is required",
+	grpcerr.FieldViolation{Field: "name", Description: "must not be empty"},
+).WithLocalizedMessage("en-US", "Please tell us your name.")
+
+// validateRequest checks the fields of a HelloRequest shared by every RPC.
+func validateRequest(req interface{ GetName() string }) error {
+	if req.GetName() == "" {
+		return ErrNameRequired
+	}
+
+	return nil
+}
+
+// ErrTooManyMessages is returned when a client sends more messages on a stream than allowed.
+var ErrTooManyMessages = grpcerr.New(codes.ResourceExhausted, "STREAM_MESSAGE_LIMIT", "too many messages on stream")
+
+// ErrStreamTooLarge is returned when the messages a client sends on a stream exceed the allowed total size.
+var ErrStreamTooLarge = grpcerr.New(codes.ResourceExhausted, "STREAM_SIZE_LIMIT",
+	"stream exceeded the maximum total size")
+
+// ErrChatClosed is returned to Chat members when the server shuts down.
+var ErrChatClosed = grpcerr.Unavailable("CHAT_CLOSED", "chat is shutting down", time.Second)
+
+// ErrSlowConsumer is returned to a Chat member evicted because it did not keep up with its room.
+var ErrSlowConsumer = grpcerr.New(codes.ResourceExhausted, "CHAT_SLOW_CONSUMER",
+	"evicted from the chat room for not keeping up")
+
+// ErrInvalidChatRoom is returned when the 'x-chat-room' metadata header of a Chat stream is not a valid room name.
+var ErrInvalidChatRoom = grpcerr.InvalidArgument("INVALID_CHAT_ROOM", "invalid chat room",
+	grpcerr.FieldViolation{Field: "x-chat-room", Description: "must be at most 64 letters, digits, '.', '_' or '-'"},
+)
+
+// ErrInvalidResumeToken is returned when a StreamGreetings request carries a resume token that was not issued for it.
+var ErrInvalidResumeToken = grpcerr.InvalidArgument("INVALID_RESUME_TOKEN", "invalid resume token",
+	grpcerr.FieldViolation{
+		Field:       "resume_token",
+		Description: "must be a token received from a previous stream for the same name",
+	},
+)
+
+// ErrHistoryDisabled is returned by the history RPCs when the service runs without a store.
+var ErrHistoryDisabled = grpcerr.New(codes.Unimplemented, "HISTORY_DISABLED", "greeting history is disabled")
+
+// ErrTooManyToOrder is returned when ListGreetings is asked to sort more greetings than allowed by a custom
+// order_by.
+var ErrTooManyToOrder = grpcerr.New(codes.FailedPrecondition, "TOO_MANY_TO_ORDER",
+	"too many greetings to sort, narrow the list with name, since or until or use the default order")
+
+// ErrGreetingNotFound is returned when a greeting does not exist.
+var ErrGreetingNotFound = grpcerr.NotFound("GREETING_NOT_FOUND", "greeting not found")
@@ -0,0 +1,126 @@
+package greeter
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/i18n"
+	"github.com/mrityunjoydey/go-grpc/src/common/constant"
+)
+
+// Greeting message keys. Every key must be defined in the default locale.
+const (
+	greetingHello  = "hello"
+	greetingStream = "stream"
+	greetingMany   = "many"
+)
+
+// DefaultLocale is the locale of greetings when the caller prefers no supported locale.
+const DefaultLocale = "en"
+
+// builtinGreetings holds the greetings shipped with the service.
+//
+//go:embed templates/*.yaml
+var builtinGreetings embed.FS
+
+// greeting is the data available to greeting templates.
+type greeting struct {
+	// Name is the name greeted by SayHello and StreamGreetings.
+	Name string
+	// Names are the names greeted by GreetManyTimes.
+	Names []string
+	// Index is the 1-based position of a StreamGreetings message.
+	Index int
+	// Count is the number of names or streamed messages, also used to select the plural form.
+	Count int
+}
+
+// sampleGreeting is used to check that every template renders when loading.
+var sampleGreeting = greeting{Name: "World", Names: []string{"Alice", "Bob"}, Index: 1, Count: 2}
+
+// NewGreetings creates the greeting catalog: the built-in greetings, overridden by the "<locale>.yaml" files
+// in dir when set. Every template is checked, so invalid files fail here rather than at request time.
+func NewGreetings(dir, defaultLocale string) (*i18n.Catalog, error) {
+	load, err := greetingsLoader(dir, defaultLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	return i18n.NewCatalog(load)
+}
+
+// ReloadGreetings points a catalog created by NewGreetings to another directory or default locale. The current
+// greetings are kept when the new ones are invalid.
+func ReloadGreetings(c *i18n.Catalog, dir, defaultLocale string) error {
+	load, err := greetingsLoader(dir, defaultLocale)
+	if err != nil {
+		return err
+	}
+
+	return c.Replace(load)
+}
+
+// greetingsLoader returns the function loading the greetings of NewGreetings.
+func greetingsLoader(dir, defaultLocale string) (func() (*i18n.Bundle, error), error) {
+	fallback, err := language.Parse(defaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default locale %q: %w", defaultLocale, err)
+	}
+
+	return func() (*i18n.Bundle, error) {
+		b := i18n.NewBundle(fallback)
+
+		builtin, err := fs.Sub(builtinGreetings, "templates")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := b.LoadFS(builtin); err != nil {
+			return nil, err
+		}
+
+		if dir != "" {
+			if err := b.LoadFS(os.DirFS(dir)); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := b.Check(sampleGreeting, greetingHello, greetingStream, greetingMany); err != nil {
+			return nil, err
+		}
+
+		return b, nil
+	}, nil
+}
+
+// mustBuiltinGreetings returns the catalog of the built-in greetings, which are checked by the tests.
+func mustBuiltinGreetings() *i18n.Catalog {
+	c, err := NewGreetings("", DefaultLocale)
+	if err != nil {
+		panic("invalid built-in greetings: " + err.Error())
+	}
+
+	return c
+}
+
+// greet renders a greeting in the locale preferred by the caller: the locale of the request, then the
+// 'accept-language' metadata header, then the default locale. It returns the locale the greeting was rendered in.
+func (s *Service) greet(ctx context.Context, locale, key string, data greeting) (string, string, error) {
+	var acceptLanguage []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		acceptLanguage = md.Get(string(constant.AcceptLanguageHeader))
+	}
+
+	msg, tag, err := s.greetings.Bundle().Render(i18n.Preferences(locale, acceptLanguage...), key, data.Count, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	return msg, tag.String(), nil
+}
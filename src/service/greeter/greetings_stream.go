@@ -0,0 +1,107 @@
+package greeter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resumeTokenVersion prefixes resume tokens so that their format can evolve.
+const resumeTokenVersion = "v1"
+
+// StreamGreetingsLimits bounds the greetings a StreamGreetings call may ask for.
+type StreamGreetingsLimits struct {
+	// DefaultCount is the number of greetings sent when the request sets no count.
+	DefaultCount int
+	// MaxCount caps the requested number of greetings. Zero disables the cap.
+	MaxCount int
+	// MaxInterval caps the requested pause between two greetings. Zero disables the cap.
+	MaxInterval time.Duration
+}
+
+// plan returns the number of greetings and the pause between them for the request, capped by the limits.
+func (l StreamGreetingsLimits) plan(count uint32, intervalMs uint32) (int, time.Duration) {
+	n := int(count)
+	if n == 0 {
+		n = l.DefaultCount
+	}
+
+	if l.MaxCount > 0 && n > l.MaxCount {
+		n = l.MaxCount
+	}
+
+	interval := time.Duration(intervalMs) * time.Millisecond
+	if l.MaxInterval > 0 && interval > l.MaxInterval {
+		interval = l.MaxInterval
+	}
+
+	return n, interval
+}
+
+// sleep waits for the duration, returning early with the context error once the context ends.
+func sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resumeToken returns the opaque token resuming a StreamGreetings stream of the given name after the greeting
+// with the given sequence number. The token is signed with the key, binding it to the name.
+func resumeToken(key []byte, name string, sequence int) string {
+	payload := resumeTokenVersion + ":" + strconv.Itoa(sequence)
+
+	// A single base64url value, the only characters the resume_token field accepts
+	return base64.RawURLEncoding.EncodeToString(append([]byte(payload), signResumeToken(key, payload, name)...))
+}
+
+// parseResumeToken returns the sequence number of the last greeting received, or 0 without a token.
+// Tokens that were not signed with the key for the same name are rejected.
+func parseResumeToken(key []byte, token, name string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(decoded) <= sha256.Size {
+		return 0, ErrInvalidResumeToken
+	}
+
+	payload, mac := decoded[:len(decoded)-sha256.Size], decoded[len(decoded)-sha256.Size:]
+	if !hmac.Equal(mac, signResumeToken(key, string(payload), name)) {
+		return 0, ErrInvalidResumeToken
+	}
+
+	version, value, ok := strings.Cut(string(payload), ":")
+	if !ok || version != resumeTokenVersion {
+		return 0, ErrInvalidResumeToken
+	}
+
+	sequence, err := strconv.Atoi(value)
+	if err != nil || sequence < 0 {
+		return 0, ErrInvalidResumeToken
+	}
+
+	return sequence, nil
+}
+
+// signResumeToken signs the payload of a resume token together with the name greeted by its stream.
+func signResumeToken(key []byte, payload, name string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload + "\x00" + name))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,169 @@
+package greeter
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/pagination"
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+	"github.com/mrityunjoydey/go-grpc/src/store"
+)
+
+// newGreetingPaginator creates the paginator of ListGreetings, signing page tokens with the key. The default
+// order is the order the store keeps greetings in.
+func newGreetingPaginator(key []byte) *pagination.Paginator {
+	return pagination.New(key, "id",
+		pagination.WithDefaultOrder("create_time"),
+		pagination.WithOrderableFields("create_time", "name", "method", "locale", "room", "id"),
+	)
+}
+
+// record stores a greeting sent. Failures are logged but never fail the call that greeted.
+func (s *Service) record(ctx context.Context, g *store.Greeting) {
+	if s.store == nil {
+		return
+	}
+
+	if err := s.store.Add(ctx, g); err != nil {
+		s.logger.WithContext(ctx).Error("Failed to record greeting", zap.Error(err))
+	}
+}
+
+// recordSummary stores the greeting of every distinct name of a summary.
+func (s *Service) recordSummary(ctx context.Context, method string, summary *pb.GreetingSummary) {
+	for _, g := range summary.GetGreetings() {
+		s.record(ctx, &store.Greeting{
+			Name:    g.GetName(),
+			Message: g.GetMessage(),
+			Method:  method,
+			Locale:  summary.GetLocale(),
+		})
+	}
+}
+
+// ListGreetings implements the ListGreetings RPC method. The name, since and until fields narrow the greetings
+// read from the store, then the filter, order_by, page and read mask are applied to them.
+func (s *Service) ListGreetings(ctx context.Context, req *pb.ListGreetingsRequest) (*pb.ListGreetingsResponse, error) {
+	if s.store == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	list, err := s.paginator.Parse((&pb.Greeting{}).ProtoReflect().Descriptor(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	q := store.Query{Name: req.GetName()}
+
+	if req.GetSince() != nil {
+		q.Since = req.GetSince().AsTime()
+	}
+
+	if req.GetUntil() != nil {
+		q.Until = req.GetUntil().AsTime()
+	}
+
+	var greetings []*pb.Greeting
+
+	if list.DefaultOrder() {
+		greetings, err = s.readPage(ctx, q, list)
+	} else {
+		greetings, err = s.readAll(ctx, q)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	page, next := pagination.Page(list, greetings)
+
+	return &pb.ListGreetingsResponse{Greetings: page, NextPageToken: next}, nil
+}
+
+// readPage reads the greetings of a page listed in the default order, the creation order the store keeps them
+// in. It reads from the cursor of the previous page until the filter kept one greeting more than the page, which
+// tells Page to issue the next page token.
+func (s *Service) readPage(ctx context.Context, q store.Query, list *pagination.List) ([]*pb.Greeting, error) {
+	q.After, q.Limit = list.After(), list.Size()+1
+
+	var greetings []*pb.Greeting
+
+	for {
+		stored, err := s.store.List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, g := range stored.Greetings {
+			if p := greetingProto(g); list.Match(p) {
+				greetings = append(greetings, p)
+			}
+		}
+
+		if len(greetings) > list.Size() || stored.Next == "" {
+			return greetings, nil
+		}
+
+		q.After = stored.Next
+	}
+}
+
+// readAll reads every greeting selected by the query, to be ordered by a custom order_by. Since every page sorts
+// them all, it fails with ErrTooManyToOrder rather than read more than the maximum.
+func (s *Service) readAll(ctx context.Context, q store.Query) ([]*pb.Greeting, error) {
+	if s.maxOrdered > 0 {
+		q.Limit = s.maxOrdered + 1
+	}
+
+	stored, err := s.store.List(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.maxOrdered > 0 && len(stored.Greetings) > s.maxOrdered {
+		return nil, ErrTooManyToOrder.WithMetadata("max", strconv.Itoa(s.maxOrdered))
+	}
+
+	greetings := make([]*pb.Greeting, 0, len(stored.Greetings))
+	for _, g := range stored.Greetings {
+		greetings = append(greetings, greetingProto(g))
+	}
+
+	return greetings, nil
+}
+
+// GetGreeting implements the GetGreeting RPC method.
+func (s *Service) GetGreeting(ctx context.Context, req *pb.GetGreetingRequest) (*pb.Greeting, error) {
+	if s.store == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	g, err := s.store.Get(ctx, req.GetId())
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrGreetingNotFound.WithMetadata("id", req.GetId())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return greetingProto(g), nil
+}
+
+// greetingProto converts a stored greeting to its API representation.
+func greetingProto(g *store.Greeting) *pb.Greeting {
+	return &pb.Greeting{
+		Id:         g.ID,
+		Name:       g.Name,
+		Message:    g.Message,
+		Method:     g.Method,
+		Locale:     g.Locale,
+		Room:       g.Room,
+		Sender:     g.Sender,
+		CreateTime: timestamppb.New(g.CreatedAt),
+	}
+}
@@ -0,0 +1,147 @@
+package greeter
+
+import (
+	"sync"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+// defaultChatBufferSize is the default number of messages buffered per Chat room member.
+const defaultChatBufferSize = 64
+
+// subscriber is a Chat room member receiving the messages published to its room.
+type subscriber struct {
+	name     string
+	room     string
+	messages chan *pb.HelloReply
+	done     chan struct{}
+	once     sync.Once
+	err      error
+}
+
+// close ends the subscription with the given reason. Only the first reason is kept.
+func (s *subscriber) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+// Err returns the reason the subscription ended, once done is closed.
+func (s *subscriber) Err() error {
+	<-s.done
+	return s.err
+}
+
+// hub fans out Chat messages to the members of each room. Every member has a bounded buffer: a member
+// that does not keep up is evicted instead of slowing down the room. It is safe for concurrent use.
+type hub struct {
+	mu         sync.Mutex
+	rooms      map[string]map[*subscriber]struct{}
+	bufferSize int
+	closed     bool
+}
+
+func newHub(bufferSize int) *hub {
+	if bufferSize <= 0 {
+		bufferSize = defaultChatBufferSize
+	}
+
+	return &hub{rooms: make(map[string]map[*subscriber]struct{}), bufferSize: bufferSize}
+}
+
+// join adds a new member to the room and announces it to every member, including the new one.
+func (h *hub) join(room, name string) (*subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrChatClosed
+	}
+
+	sub := &subscriber{
+		name:     name,
+		room:     room,
+		messages: make(chan *pb.HelloReply, h.bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*subscriber]struct{})
+	}
+
+	h.rooms[room][sub] = struct{}{}
+
+	h.publishLocked(room, &pb.HelloReply{
+		Message: name + " joined " + room,
+		Room:    room,
+		Sender:  name,
+		Event:   pb.ChatEvent_CHAT_EVENT_JOIN,
+	})
+
+	return sub, nil
+}
+
+// leave removes the member from its room and announces it to the remaining members.
+func (h *hub) leave(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub.close(nil)
+
+	// Evicted members were already removed, but their departure is still announced
+	members, ok := h.rooms[sub.room]
+	if !ok {
+		return
+	}
+
+	delete(members, sub)
+
+	if len(members) == 0 {
+		delete(h.rooms, sub.room)
+		return
+	}
+
+	h.publishLocked(sub.room, &pb.HelloReply{
+		Message: sub.name + " left " + sub.room,
+		Room:    sub.room,
+		Sender:  sub.name,
+		Event:   pb.ChatEvent_CHAT_EVENT_LEAVE,
+	})
+}
+
+// publish sends the message to every member of the room.
+func (h *hub) publish(room string, msg *pb.HelloReply) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.publishLocked(room, msg)
+}
+
+func (h *hub) publishLocked(room string, msg *pb.HelloReply) {
+	for sub := range h.rooms[room] {
+		select {
+		case sub.messages <- msg:
+		default:
+			// The member's buffer is full: evict it rather than block the room
+			delete(h.rooms[room], sub)
+			sub.close(ErrSlowConsumer)
+		}
+	}
+}
+
+// close ends every subscription and rejects new members.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+
+	for room, members := range h.rooms {
+		for sub := range members {
+			sub.close(ErrChatClosed)
+		}
+
+		delete(h.rooms, room)
+	}
+}
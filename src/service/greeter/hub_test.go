@@ -0,0 +1,98 @@
+package greeter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+// receive returns the messages buffered for the subscriber.
+func receive(sub *subscriber) []*pb.HelloReply {
+	var msgs []*pb.HelloReply
+
+	for {
+		select {
+		case msg := <-sub.messages:
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestHub_FanOut(t *testing.T) {
+	h := newHub(8)
+
+	alice, err := h.join("general", "Alice")
+	require.NoError(t, err)
+
+	bob, err := h.join("general", "Bob")
+	require.NoError(t, err)
+
+	other, err := h.join("random", "Carol")
+	require.NoError(t, err)
+
+	h.publish("general", &pb.HelloReply{Message: "Hello, Bob", Room: "general", Sender: "Alice"})
+	h.leave(bob)
+
+	aliceMsgs := receive(alice)
+	require.Len(t, aliceMsgs, 4)
+	assert.Equal(t, pb.ChatEvent_CHAT_EVENT_JOIN, aliceMsgs[0].Event)
+	assert.Equal(t, "Bob joined general", aliceMsgs[1].Message)
+	assert.Equal(t, "Hello, Bob", aliceMsgs[2].Message)
+	assert.Equal(t, pb.ChatEvent_CHAT_EVENT_LEAVE, aliceMsgs[3].Event)
+
+	bobMsgs := receive(bob)
+	require.Len(t, bobMsgs, 2)
+	assert.Equal(t, "Hello, Bob", bobMsgs[1].Message)
+
+	// Rooms are isolated from each other
+	assert.Len(t, receive(other), 1)
+}
+
+func TestHub_EvictsSlowConsumer(t *testing.T) {
+	h := newHub(2)
+
+	slow, err := h.join("general", "Slow")
+	require.NoError(t, err)
+
+	fast, err := h.join("general", "Fast")
+	require.NoError(t, err)
+
+	// The slow member already holds its own and the fast member's join events
+	h.publish("general", &pb.HelloReply{Message: "Hello"})
+
+	assert.ErrorIs(t, slow.Err(), ErrSlowConsumer)
+
+	select {
+	case <-fast.done:
+		t.Fatal("fast consumer must not be evicted")
+	default:
+	}
+
+	assert.Len(t, receive(fast), 2)
+
+	// The departure of an evicted member is announced when its session ends
+	h.leave(slow)
+
+	msgs := receive(fast)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, pb.ChatEvent_CHAT_EVENT_LEAVE, msgs[0].Event)
+}
+
+func TestHub_Close(t *testing.T) {
+	h := newHub(8)
+
+	sub, err := h.join("general", "Alice")
+	require.NoError(t, err)
+
+	h.close()
+
+	assert.ErrorIs(t, sub.Err(), ErrChatClosed)
+
+	_, err = h.join("general", "Bob")
+	assert.ErrorIs(t, err, ErrChatClosed)
+}
@@ -0,0 +1,55 @@
+package greeter
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+// StreamLimits bounds the resources a single client stream of GreetManyTimes, SummarizeGreetings or Chat may use.
+// Zero values disable the corresponding limit. Idle and long-lived streams are canceled by the deadline
+// interceptors instead, see middleware.DeadlinePolicy.
+type StreamLimits struct {
+	// MaxMessages is the maximum number of messages a client may send on one stream.
+	MaxMessages int
+	// MaxBytes is the maximum total encoded size of the messages a client may send on one stream.
+	MaxBytes int
+}
+
+// requestReceiver is the receiving side shared by the client and bi-directional streams.
+type requestReceiver interface {
+	Recv() (*pb.HelloRequest, error)
+}
+
+// limitedReceiver enforces StreamLimits on a requestReceiver.
+type limitedReceiver struct {
+	stream   requestReceiver
+	limits   StreamLimits
+	messages int
+	bytes    int
+}
+
+func newLimitedReceiver(stream requestReceiver, limits StreamLimits) *limitedReceiver {
+	return &limitedReceiver{stream: stream, limits: limits}
+}
+
+// Recv receives the next request, failing once a limit is exceeded.
+func (r *limitedReceiver) Recv() (*pb.HelloRequest, error) {
+	req, err := r.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	r.messages++
+	r.bytes += proto.Size(req)
+
+	if r.limits.MaxMessages > 0 && r.messages > r.limits.MaxMessages {
+		return nil, ErrTooManyMessages
+	}
+
+	if r.limits.MaxBytes > 0 && r.bytes > r.limits.MaxBytes {
+		return nil, ErrStreamTooLarge
+	}
+
+	return req, nil
+}
@@ -3,61 +3,265 @@ package greeter
 
 import (
 	"context"
-	"fmt"
+	"crypto/rand"
+	"crypto/sha256"
 	"io"
+	"regexp"
+	"sync/atomic"
 
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/flags"
+	"github.com/mrityunjoydey/go-grpc/pkg/i18n"
 	"github.com/mrityunjoydey/go-grpc/pkg/logger"
+	"github.com/mrityunjoydey/go-grpc/pkg/pagination"
 	pb "github.com/mrityunjoydey/go-grpc/rpc"
-	"go.uber.org/zap"
+	"github.com/mrityunjoydey/go-grpc/src/common/constant"
+	"github.com/mrityunjoydey/go-grpc/src/store"
 )
 
+// DefaultChatRoom is the room joined by Chat clients that do not select one.
+const DefaultChatRoom = "lobby"
+
+// maxChatRoomLength and chatRoomPattern restrict the room selected by the 'x-chat-room' metadata header, like
+// the rules of the room field.
+const maxChatRoomLength = 64
+
+var chatRoomPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// EmojiFlag is the feature flag adding a waving hand to the SayHello greetings of the callers it is on for.
+const EmojiFlag = "greeter.emoji"
+
+// defaultMaxOrderedGreetings is the default number of greetings ListGreetings sorts for a custom order_by.
+const defaultMaxOrderedGreetings = 10000
+
+// defaultStreamGreetingsCount is the default number of greetings sent by StreamGreetings.
+const defaultStreamGreetingsCount = 5
+
 // Service implements the GreeterServer interface.
 type Service struct {
 	pb.UnimplementedGreeterServer
-	logger logger.Logger
+	logger         logger.Logger
+	limits         atomic.Pointer[StreamLimits]
+	greetingLimits atomic.Pointer[StreamGreetingsLimits]
+	chatBufferSize int
+	hub            *hub
+	greetings      *i18n.Catalog
+	store          store.Store
+	pageTokenKey   []byte
+	paginator      *pagination.Paginator
+	maxOrdered     int
+	flags          *flags.Set
+}
+
+// Option configures the Service.
+type Option func(*Service)
+
+// WithStreamLimits sets the limits enforced on the GreetManyTimes, SummarizeGreetings and Chat client streams.
+func WithStreamLimits(limits StreamLimits) Option {
+	return func(s *Service) {
+		s.SetStreamLimits(limits)
+	}
+}
+
+// WithStreamGreetingsLimits sets the default and maximum count and interval of StreamGreetings calls.
+func WithStreamGreetingsLimits(limits StreamGreetingsLimits) Option {
+	return func(s *Service) {
+		s.SetStreamGreetingsLimits(limits)
+	}
+}
+
+// WithChatBufferSize sets the number of messages buffered for each Chat room member.
+// Members falling further behind are evicted from their room.
+func WithChatBufferSize(n int) Option {
+	return func(s *Service) {
+		s.chatBufferSize = n
+	}
+}
+
+// WithFlags sets the feature flags gating greeting behaviors, such as EmojiFlag. Without a set, every flag is
+// off.
+func WithFlags(set *flags.Set) Option {
+	return func(s *Service) {
+		s.flags = set
+	}
+}
+
+// WithGreetings sets the catalog the greetings are rendered from. See NewGreetings.
+// By default, only the built-in greetings are used.
+func WithGreetings(c *i18n.Catalog) Option {
+	return func(s *Service) {
+		s.greetings = c
+	}
+}
+
+// WithStore records the greetings sent in the store and serves them with ListGreetings and GetGreeting.
+// Without a store, the history RPCs fail with codes.Unimplemented.
+func WithStore(st store.Store) Option {
+	return func(s *Service) {
+		s.store = st
+	}
+}
+
+// WithMaxOrderedGreetings sets the number of greetings ListGreetings sorts at most for an order_by other than the
+// default: every page of such a list reads and sorts all the greetings the query selects. Queries selecting more
+// fail with ErrTooManyToOrder. Zero removes the limit.
+func WithMaxOrderedGreetings(n int) Option {
+	return func(s *Service) {
+		s.maxOrdered = n
+	}
+}
+
+// WithPageTokenKey sets the key signing the page tokens of ListGreetings and the resume tokens of
+// StreamGreetings. Without a key, a random one is generated, so tokens do not survive restarts.
+func WithPageTokenKey(key []byte) Option {
+	return func(s *Service) {
+		s.pageTokenKey = key
+	}
 }
 
 // NewService creates a new Service.
-func NewService(logger logger.Logger) *Service {
-	return &Service{logger: logger}
+func NewService(logger logger.Logger, opts ...Option) *Service {
+	s := &Service{
+		logger:         logger,
+		chatBufferSize: defaultChatBufferSize,
+		maxOrdered:     defaultMaxOrderedGreetings,
+	}
+	s.limits.Store(&StreamLimits{})
+	s.greetingLimits.Store(&StreamGreetingsLimits{DefaultCount: defaultStreamGreetingsCount})
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.hub = newHub(s.chatBufferSize)
+
+	if s.greetings == nil {
+		s.greetings = mustBuiltinGreetings()
+	}
+
+	if len(s.pageTokenKey) == 0 {
+		s.pageTokenKey = make([]byte, sha256.Size)
+		if _, err := rand.Read(s.pageTokenKey); err != nil {
+			panic("failed to generate page token key: " + err.Error())
+		}
+	}
+
+	s.paginator = newGreetingPaginator(s.pageTokenKey)
+
+	return s
+}
+
+// SetStreamLimits replaces the limits of the client streams, e.g. when the configuration is reloaded. Streams in
+// progress keep the limits they started with.
+func (s *Service) SetStreamLimits(limits StreamLimits) {
+	s.limits.Store(&limits)
+}
+
+// SetStreamGreetingsLimits replaces the limits of StreamGreetings calls. Calls in progress keep the limits they
+// started with.
+func (s *Service) SetStreamGreetingsLimits(limits StreamGreetingsLimits) {
+	s.greetingLimits.Store(&limits)
 }
 
 // SayHello implements the SayHello RPC method.
 func (s *Service) SayHello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloReply, error) {
 	s.logger.WithContext(ctx).Info("SayHello request received", zap.String("name", req.GetName()))
-	return &pb.HelloReply{Message: "Hello, " + req.GetName()}, nil
+
+	if err := validateRequest(req); err != nil {
+		return nil, err
+	}
+
+	message, locale, err := s.greet(ctx, req.GetLocale(), greetingHello, greeting{Name: req.GetName(), Count: 1})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.flags.Enabled(ctx, EmojiFlag) {
+		message += " 👋"
+	}
+
+	s.record(ctx, &store.Greeting{Name: req.GetName(), Message: message, Method: "SayHello", Locale: locale})
+
+	return &pb.HelloReply{Message: message, Locale: locale}, nil
 }
 
 // StreamGreetings implements the StreamGreetings RPC method for server-side streaming.
+// The request selects the number of greetings and the pause between them, within the configured limits.
+// Every greeting carries a resume token: a client reconnecting with the token of the last greeting it received
+// continues the stream after it.
 func (s *Service) StreamGreetings(req *pb.HelloRequest, stream pb.Greeter_StreamGreetingsServer) error {
-	s.logger.WithContext(stream.Context()).Info("StreamGreetings request received", zap.String("name", req.GetName()))
+	ctx := stream.Context()
+	s.logger.WithContext(ctx).Info("StreamGreetings request received", zap.String("name", req.GetName()))
+
+	if err := validateRequest(req); err != nil {
+		return err
+	}
+
+	last, err := parseResumeToken(s.pageTokenKey, req.GetResumeToken(), req.GetName())
+	if err != nil {
+		return err
+	}
+
+	count, interval := s.greetingLimits.Load().plan(req.GetCount(), req.GetIntervalMs())
+
+	for i := last + 1; i <= count; i++ {
+		if i > last+1 {
+			if err := sleep(ctx, interval); err != nil {
+				return err
+			}
+		}
+
+		message, locale, err := s.greet(ctx, req.GetLocale(), greetingStream, greeting{
+			Name:  req.GetName(),
+			Index: i,
+			Count: count,
+		})
+		if err != nil {
+			return err
+		}
 
-	for i := 0; i < 5; i++ {
 		response := &pb.HelloReply{
-			Message: fmt.Sprintf("Hello, %s! (Greeting #%d)", req.GetName(), i+1),
+			Message:     message,
+			Locale:      locale,
+			Sequence:    uint32(i),
+			ResumeToken: resumeToken(s.pageTokenKey, req.GetName(), i),
 		}
 		if err := stream.Send(response); err != nil {
-			s.logger.WithContext(stream.Context()).Error("Failed to send greeting", zap.Error(err))
+			s.logger.WithContext(ctx).Error("Failed to send greeting", zap.Error(err))
 			return err
 		}
 
-		s.logger.WithContext(stream.Context()).Info("Sent greeting", zap.String("message", response.GetMessage()))
+		s.logger.WithContext(ctx).Info("Sent greeting", zap.String("message", response.GetMessage()))
 	}
 
 	return nil
 }
 
 // GreetManyTimes implements the GreetManyTimes RPC method for client-side streaming.
+// The reply message greets the distinct names received, and its summary details them.
 func (s *Service) GreetManyTimes(stream pb.Greeter_GreetManyTimesServer) error {
 	s.logger.WithContext(stream.Context()).Info("GreetManyTimes request received")
 
-	var names []string
+	recv := newLimitedReceiver(stream, *s.limits.Load())
+	tally := newNameTally()
 
 	for {
-		req, err := stream.Recv()
+		req, err := recv.Recv()
 		if err == io.EOF {
-			message := fmt.Sprintf("Hello, %v!", names)
-			return stream.SendAndClose(&pb.HelloReply{Message: message})
+			summary, err := s.summarize(stream.Context(), tally, true)
+			if err != nil {
+				return err
+			}
+
+			s.recordSummary(stream.Context(), "GreetManyTimes", summary)
+
+			return stream.SendAndClose(&pb.HelloReply{
+				Message: summary.GetMessage(),
+				Locale:  summary.GetLocale(),
+				Summary: summary,
+			})
 		}
 
 		if err != nil {
@@ -66,33 +270,230 @@ func (s *Service) GreetManyTimes(stream pb.Greeter_GreetManyTimesServer) error {
 		}
 
 		s.logger.WithContext(stream.Context()).Info("Received name", zap.String("name", req.GetName()))
-		names = append(names, req.GetName())
+
+		if err := validateRequest(req); err != nil {
+			return err
+		}
+
+		tally.add(req)
 	}
 }
 
-// Chat implements the Chat RPC method for bi-directional streaming.
-func (s *Service) Chat(stream pb.Greeter_ChatServer) error {
-	s.logger.WithContext(stream.Context()).Info("Chat session started")
+// SummarizeGreetings implements the SummarizeGreetings RPC method. It collects names like GreetManyTimes,
+// sends an interim summary after every request asking for one, and a final summary once the client closes
+// its side of the stream.
+func (s *Service) SummarizeGreetings(stream pb.Greeter_SummarizeGreetingsServer) error {
+	s.logger.WithContext(stream.Context()).Info("SummarizeGreetings request received")
+
+	recv := newLimitedReceiver(stream, *s.limits.Load())
+	tally := newNameTally()
 
 	for {
-		req, err := stream.Recv()
+		req, err := recv.Recv()
 		if err == io.EOF {
-			return nil
+			summary, err := s.summarize(stream.Context(), tally, true)
+			if err != nil {
+				return err
+			}
+
+			s.recordSummary(stream.Context(), "SummarizeGreetings", summary)
+
+			return stream.Send(summary)
 		}
 
 		if err != nil {
-			s.logger.WithContext(stream.Context()).Error("Failed to receive message", zap.Error(err))
+			s.logger.WithContext(stream.Context()).Error("Failed to receive request", zap.Error(err))
 			return err
 		}
 
-		s.logger.WithContext(stream.Context()).Info("Received message", zap.String("name", req.GetName()))
-		response := &pb.HelloReply{
-			Message: "Hello, " + req.GetName(),
+		if err := validateRequest(req); err != nil {
+			return err
 		}
 
-		if err := stream.Send(response); err != nil {
-			s.logger.WithContext(stream.Context()).Error("Failed to send message", zap.Error(err))
+		tally.add(req)
+
+		if !req.GetSummarize() {
+			continue
+		}
+
+		summary, err := s.summarize(stream.Context(), tally, false)
+		if err != nil {
 			return err
 		}
+
+		if err := stream.Send(summary); err != nil {
+			s.logger.WithContext(stream.Context()).Error("Failed to send summary", zap.Error(err))
+			return err
+		}
+	}
+}
+
+// Chat implements the Chat RPC method for bi-directional streaming.
+// The client joins a room, chosen by the 'x-chat-room' metadata header or else the room of its first message,
+// and every greeting it sends is broadcast to all members of the room along with join and leave events.
+func (s *Service) Chat(stream pb.Greeter_ChatServer) error {
+	s.logger.WithContext(stream.Context()).Info("Chat session started")
+
+	recv := newLimitedReceiver(stream, *s.limits.Load())
+
+	first, err := recv.Recv()
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		s.logger.WithContext(stream.Context()).Error("Failed to receive message", zap.Error(err))
+		return err
+	}
+
+	if err := validateRequest(first); err != nil {
+		return err
+	}
+
+	room, err := chatRoom(stream.Context(), first)
+	if err != nil {
+		return err
+	}
+
+	sub, err := s.hub.join(room, first.GetName())
+	if err != nil {
+		return err
+	}
+
+	defer s.hub.leave(sub)
+
+	log := s.logger.WithContext(stream.Context()).With(zap.String("room", sub.room))
+	log.Info("Joined chat room", zap.String("name", sub.name))
+
+	s.publishChat(stream.Context(), sub, first)
+
+	// Messages are received on their own goroutine so that the room keeps being delivered meanwhile. They are
+	// published here rather than by that goroutine, so that nothing is published once the member left the room.
+	received := make(chan chatReceived)
+	done := make(chan struct{})
+
+	defer close(done)
+
+	go receiveChat(recv, received, done)
+
+	for {
+		select {
+		case msg := <-sub.messages:
+			if err := stream.Send(msg); err != nil {
+				log.Error("Failed to send message", zap.Error(err))
+				return err
+			}
+		case m := <-received:
+			if m.err == io.EOF {
+				// The client closed its side: deliver what is already buffered, then end the session
+				return s.drainChat(stream, sub)
+			}
+
+			if m.err != nil {
+				log.Error("Failed to receive message", zap.Error(m.err))
+				return m.err
+			}
+
+			log.Info("Received message", zap.String("name", m.req.GetName()))
+
+			if err := validateRequest(m.req); err != nil {
+				return err
+			}
+
+			s.publishChat(stream.Context(), sub, m.req)
+		case <-sub.done:
+			log.Info("Chat session ended by the room", zap.Error(sub.Err()))
+			return sub.Err()
+		}
+	}
+}
+
+// chatReceived is a greeting received from a Chat member, or the error ending its side of the session.
+type chatReceived struct {
+	req *pb.HelloRequest
+	err error
+}
+
+// receiveChat hands every greeting received from the member to Chat, until the stream fails or Chat returned.
+// gRPC only interrupts a pending receive once the handler returned, so the receive pending when Chat returns
+// ends with the stream and its message is dropped.
+func receiveChat(recv *limitedReceiver, received chan<- chatReceived, done <-chan struct{}) {
+	for {
+		req, err := recv.Recv()
+
+		select {
+		case received <- chatReceived{req: req, err: err}:
+		case <-done:
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// drainChat sends the messages already buffered for the member.
+func (s *Service) drainChat(stream pb.Greeter_ChatServer, sub *subscriber) error {
+	for {
+		select {
+		case msg := <-sub.messages:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// Close ends every Chat session. It must be called before the gRPC server stops gracefully, as Chat streams
+// otherwise stay open until their clients leave.
+func (s *Service) Close() {
+	s.hub.close()
+}
+
+// chatRoom returns the room selected by the 'x-chat-room' metadata header, the first message or the default.
+// The header is checked against the rules of the room field, which the validation interceptors already enforce.
+func chatRoom(ctx context.Context, first *pb.HelloRequest) (string, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		// gRPC metadata keys are automatically lowercased
+		if values := md.Get(string(constant.ChatRoomHeader)); len(values) > 0 && values[0] != "" {
+			if len(values[0]) > maxChatRoomLength || !chatRoomPattern.MatchString(values[0]) {
+				return "", ErrInvalidChatRoom
+			}
+
+			return values[0], nil
+		}
+	}
+
+	if first.GetRoom() != "" {
+		return first.GetRoom(), nil
+	}
+
+	return DefaultChatRoom, nil
+}
+
+// publishChat broadcasts a greeting sent by a member to its room and records it.
+func (s *Service) publishChat(ctx context.Context, sub *subscriber, req *pb.HelloRequest) {
+	msg := chatMessage(sub, req)
+	s.hub.publish(sub.room, msg)
+
+	s.record(ctx, &store.Greeting{
+		Name:    req.GetName(),
+		Message: msg.GetMessage(),
+		Method:  "Chat",
+		Room:    msg.GetRoom(),
+		Sender:  msg.GetSender(),
+	})
+}
+
+// chatMessage builds the broadcast of a greeting sent by a member.
+func chatMessage(sub *subscriber, req *pb.HelloRequest) *pb.HelloReply {
+	return &pb.HelloReply{
+		Message: "Hello, " + req.GetName(),
+		Room:    sub.room,
+		Sender:  sub.name,
+		Event:   pb.ChatEvent_CHAT_EVENT_MESSAGE,
 	}
 }
@@ -2,15 +2,36 @@ package greeter_test
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mrityunjoydey/go-grpc/src/service/greeter"
 	"github.com/mrityunjoydey/go-grpc/pkg/logger"
 	pb "github.com/mrityunjoydey/go-grpc/rpc"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mrityunjoydey/go-grpc/pkg/grpcerr"
+	"github.com/mrityunjoydey/go-grpc/pkg/pagination"
+	"github.com/mrityunjoydey/go-grpc/src/middleware"
+	"github.com/mrityunjoydey/go-grpc/src/store"
 )
 
 // mockGreeterServerStream is a mock implementation of the stream interfaces.
@@ -20,6 +41,8 @@ type mockGreeterServerStream struct {
 	sentReplies []*pb.HelloReply
 	recvIndex   int
 	finalReply  *pb.HelloReply
+	// blockWhenDrained makes Recv block until the context ends once all requests were received.
+	blockWhenDrained bool
 }
 
 // Implement grpc.ServerStream
@@ -38,6 +61,11 @@ func (m *mockGreeterServerStream) Send(res *pb.HelloReply) error {
 
 func (m *mockGreeterServerStream) Recv() (*pb.HelloRequest, error) {
 	if m.recvIndex >= len(m.requests) {
+		if m.blockWhenDrained {
+			<-m.ctx.Done()
+			return nil, m.ctx.Err()
+		}
+
 		return nil, io.EOF
 	}
 
@@ -64,6 +92,20 @@ func TestGreeterService_SayHello(t *testing.T) {
 	assert.Equal(t, "Hello, World", res.Message)
 }
 
+func TestGreeterService_SayHello_NameRequired(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	res, err := s.SayHello(context.Background(), &pb.HelloRequest{})
+
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, greeter.ErrNameRequired)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.NotEmpty(t, st.Details())
+}
+
 func TestGreeterService_StreamGreetings(t *testing.T) {
 	logger, _ := logger.NewZapLogger("test", false)
 	s := greeter.NewService(logger)
@@ -79,7 +121,137 @@ func TestGreeterService_StreamGreetings(t *testing.T) {
 	for i, msg := range stream.sentReplies {
 		expected := fmt.Sprintf("Hello, Streamer! (Greeting #%d)", i+1)
 		assert.Equal(t, expected, msg.Message)
+		assert.Equal(t, uint32(i+1), msg.GetSequence())
+	}
+}
+
+func TestGreeterService_StreamGreetings_Parameters(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger, greeter.WithStreamGreetingsLimits(greeter.StreamGreetingsLimits{
+		DefaultCount: 3,
+		MaxCount:     10,
+	}))
+
+	// resumeAfter streams the first greetings and returns the token of the given greeting
+	resumeAfter := func(t *testing.T, name string, sequence int) string {
+		stream := &mockGreeterServerStream{ctx: context.Background()}
+		require.NoError(t, s.StreamGreetings(&pb.HelloRequest{Name: name, Count: uint32(sequence)}, stream))
+
+		return stream.sentReplies[sequence-1].GetResumeToken()
+	}
+
+	// forge replaces the sequence number of a token, keeping its signature
+	forge := func(token string, sequence int) string {
+		decoded, _ := base64.RawURLEncoding.DecodeString(token)
+		signature := decoded[len(decoded)-sha256.Size:]
+
+		return base64.RawURLEncoding.EncodeToString(append([]byte(fmt.Sprintf("v1:%d", sequence)), signature...))
 	}
+
+	other := greeter.NewService(logger, greeter.WithPageTokenKey([]byte("another key")))
+	otherStream := &mockGreeterServerStream{ctx: context.Background()}
+	require.NoError(t, other.StreamGreetings(&pb.HelloRequest{Name: "Streamer", Count: 1}, otherStream))
+
+	tests := []struct {
+		name              string
+		req               *pb.HelloRequest
+		expectedSequences []uint32
+		expectedErr       error
+	}{
+		{
+			name:              "default count",
+			req:               &pb.HelloRequest{Name: "Streamer"},
+			expectedSequences: []uint32{1, 2, 3},
+		},
+		{
+			name:              "requested count",
+			req:               &pb.HelloRequest{Name: "Streamer", Count: 2},
+			expectedSequences: []uint32{1, 2},
+		},
+		{
+			name:              "count capped to the maximum",
+			req:               &pb.HelloRequest{Name: "Streamer", Count: 1000},
+			expectedSequences: []uint32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+		},
+		{
+			name:              "resume after the last greeting received",
+			req:               &pb.HelloRequest{Name: "Streamer", Count: 5, ResumeToken: resumeAfter(t, "Streamer", 2)},
+			expectedSequences: []uint32{3, 4, 5},
+		},
+		{
+			name: "resume after the last greeting",
+			req:  &pb.HelloRequest{Name: "Streamer", Count: 2, ResumeToken: resumeAfter(t, "Streamer", 2)},
+		},
+		{
+			name:        "resume token of another name",
+			req:         &pb.HelloRequest{Name: "Streamer", ResumeToken: resumeAfter(t, "Other", 1)},
+			expectedErr: greeter.ErrInvalidResumeToken,
+		},
+		{
+			name:        "forged resume token",
+			req:         &pb.HelloRequest{Name: "Streamer", ResumeToken: forge(resumeAfter(t, "Streamer", 1), 4)},
+			expectedErr: greeter.ErrInvalidResumeToken,
+		},
+		{
+			name:        "resume token signed with another key",
+			req:         &pb.HelloRequest{Name: "Streamer", ResumeToken: otherStream.sentReplies[0].GetResumeToken()},
+			expectedErr: greeter.ErrInvalidResumeToken,
+		},
+		{
+			name:        "malformed resume token",
+			req:         &pb.HelloRequest{Name: "Streamer", ResumeToken: "garbage"},
+			expectedErr: greeter.ErrInvalidResumeToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &mockGreeterServerStream{ctx: context.Background()}
+
+			err := s.StreamGreetings(tt.req, stream)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			var sequences []uint32
+			for _, reply := range stream.sentReplies {
+				sequences = append(sequences, reply.GetSequence())
+				assert.NotEmpty(t, reply.GetResumeToken())
+			}
+
+			assert.Equal(t, tt.expectedSequences, sequences)
+		})
+	}
+}
+
+func TestGreeterService_StreamGreetings_Interval(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger, greeter.WithStreamGreetingsLimits(greeter.StreamGreetingsLimits{
+		DefaultCount: 5,
+		MaxInterval:  50 * time.Millisecond,
+	}))
+
+	// The requested interval is capped, so the stream completes quickly
+	start := time.Now()
+	stream := &mockGreeterServerStream{ctx: context.Background()}
+
+	require.NoError(t, s.StreamGreetings(&pb.HelloRequest{Name: "Streamer", IntervalMs: 60000}, stream))
+	assert.Len(t, stream.sentReplies, 5)
+	assert.GreaterOrEqual(t, time.Since(start), 4*50*time.Millisecond)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// Cancellation stops the stream between two greetings
+	ctx, cancel := context.WithTimeout(context.Background(), 75*time.Millisecond)
+	defer cancel()
+
+	stream = &mockGreeterServerStream{ctx: ctx}
+
+	err := s.StreamGreetings(&pb.HelloRequest{Name: "Streamer", IntervalMs: 50}, stream)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, stream.sentReplies, 2)
 }
 
 func TestGreeterService_GreetManyTimes(t *testing.T) {
@@ -89,6 +261,7 @@ func TestGreeterService_GreetManyTimes(t *testing.T) {
 	requests := []*pb.HelloRequest{
 		{Name: "Alice"},
 		{Name: "Bob"},
+		{Name: "Alice"},
 		{Name: "Charlie"},
 	}
 	stream := &mockGreeterServerStream{ctx: context.Background(), requests: requests}
@@ -96,8 +269,225 @@ func TestGreeterService_GreetManyTimes(t *testing.T) {
 	err := s.GreetManyTimes(stream)
 
 	assert.NoError(t, err)
-	assert.NotNil(t, stream.finalReply)
-	assert.Equal(t, "Hello, [Alice Bob Charlie]!", stream.finalReply.Message)
+	require.NotNil(t, stream.finalReply)
+	assert.Equal(t, "Hello, Alice, Bob, Charlie!", stream.finalReply.Message)
+
+	summary := stream.finalReply.GetSummary()
+	require.NotNil(t, summary)
+	assert.Equal(t, []string{"Alice", "Bob", "Charlie"}, summary.GetNames())
+	assert.Equal(t, uint32(4), summary.GetCount())
+	assert.Equal(t, uint32(3), summary.GetUniqueCount())
+	assert.True(t, summary.GetFinal())
+	require.Len(t, summary.GetGreetings(), 3)
+	assert.Equal(t, "Hello, Alice", summary.GetGreetings()[0].GetMessage())
+	assert.Equal(t, uint32(2), summary.GetGreetings()[0].GetTimes())
+	assert.Equal(t, uint32(1), summary.GetGreetings()[1].GetTimes())
+}
+
+// mockSummaryStream is a mock implementation of the SummarizeGreetings stream.
+type mockSummaryStream struct {
+	*mockGreeterServerStream
+	summaries []*pb.GreetingSummary
+}
+
+func (m *mockSummaryStream) Send(summary *pb.GreetingSummary) error {
+	m.summaries = append(m.summaries, summary)
+	return nil
+}
+
+func TestGreeterService_SummarizeGreetings(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	stream := &mockSummaryStream{mockGreeterServerStream: &mockGreeterServerStream{
+		ctx: context.Background(),
+		requests: []*pb.HelloRequest{
+			{Name: "Alice", Locale: "fr"},
+			{Name: "Bob", Summarize: true},
+			{Name: "Bob"},
+			{Name: "Charlie", Summarize: true},
+			{Name: "Dave"},
+		},
+	}}
+
+	require.NoError(t, s.SummarizeGreetings(stream))
+	require.Len(t, stream.summaries, 3)
+
+	interim := stream.summaries[0]
+	assert.False(t, interim.GetFinal())
+	assert.Equal(t, []string{"Alice", "Bob"}, interim.GetNames())
+	assert.Equal(t, "Bonjour à vous 2, Alice, Bob !", interim.GetMessage())
+	assert.Equal(t, "fr", interim.GetLocale())
+
+	interim = stream.summaries[1]
+	assert.False(t, interim.GetFinal())
+	assert.Equal(t, uint32(4), interim.GetCount())
+	assert.Equal(t, uint32(3), interim.GetUniqueCount())
+
+	final := stream.summaries[2]
+	assert.True(t, final.GetFinal())
+	assert.Equal(t, []string{"Alice", "Bob", "Charlie", "Dave"}, final.GetNames())
+	assert.Equal(t, "Bonjour, Dave", final.GetGreetings()[3].GetMessage())
+}
+
+func TestGreeterService_SummarizeGreetings_NameRequired(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	stream := &mockSummaryStream{mockGreeterServerStream: &mockGreeterServerStream{
+		ctx:      context.Background(),
+		requests: []*pb.HelloRequest{{Name: "Alice", Summarize: true}, {}},
+	}}
+
+	assert.ErrorIs(t, s.SummarizeGreetings(stream), greeter.ErrNameRequired)
+	assert.Len(t, stream.summaries, 1)
+}
+
+func TestGreeterService_Localized(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	tests := []struct {
+		name           string
+		locale         string
+		acceptLanguage string
+		expected       string
+		expectedLocale string
+	}{
+		{
+			name:           "default locale",
+			expected:       "Hello, World",
+			expectedLocale: "en",
+		},
+		{
+			name:           "request locale",
+			locale:         "fr",
+			expected:       "Bonjour, World",
+			expectedLocale: "fr",
+		},
+		{
+			name:           "accept-language header",
+			acceptLanguage: "de, es;q=0.8",
+			expected:       "Hola, World",
+			expectedLocale: "es",
+		},
+		{
+			name:           "request locale takes precedence over the header",
+			locale:         "fr-CA",
+			acceptLanguage: "es",
+			expected:       "Bonjour, World",
+			expectedLocale: "fr",
+		},
+		{
+			name:           "unsupported locale falls back to the default",
+			locale:         "ja",
+			expected:       "Hello, World",
+			expectedLocale: "en",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.acceptLanguage != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("accept-language", tt.acceptLanguage))
+			}
+
+			res, err := s.SayHello(ctx, &pb.HelloRequest{Name: "World", Locale: tt.locale})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, res.GetMessage())
+			assert.Equal(t, tt.expectedLocale, res.GetLocale())
+		})
+	}
+}
+
+func TestGreeterService_GreetManyTimes_Plural(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	tests := []struct {
+		name     string
+		requests []*pb.HelloRequest
+		expected string
+	}{
+		{
+			name:     "one",
+			requests: []*pb.HelloRequest{{Name: "Alice", Locale: "fr"}},
+			expected: "Bonjour, Alice !",
+		},
+		{
+			name:     "other",
+			requests: []*pb.HelloRequest{{Name: "Alice", Locale: "fr"}, {Name: "Bob"}},
+			expected: "Bonjour à vous 2, Alice, Bob !",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &mockGreeterServerStream{ctx: context.Background(), requests: tt.requests}
+
+			require.NoError(t, s.GreetManyTimes(stream))
+			assert.Equal(t, tt.expected, stream.finalReply.GetMessage())
+		})
+	}
+}
+
+func TestNewGreetings(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en.yaml"), []byte(`hello: "Hi there, {{.Name}}"`), 0o600))
+
+	greetings, err := greeter.NewGreetings(dir, "en")
+	require.NoError(t, err)
+
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger, greeter.WithGreetings(greetings))
+
+	// Files override the built-in greetings per key
+	res, err := s.SayHello(context.Background(), &pb.HelloRequest{Name: "World"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there, World", res.GetMessage())
+
+	stream := &mockGreeterServerStream{ctx: context.Background()}
+	require.NoError(t, s.StreamGreetings(&pb.HelloRequest{Name: "World"}, stream))
+	assert.Equal(t, "Hello, World! (Greeting #1)", stream.sentReplies[0].GetMessage())
+
+	// Invalid templates are rejected when loading
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fr.yaml"), []byte(`hello: "Bonjour, {{.Nom}}"`), 0o600))
+
+	_, err = greeter.NewGreetings(dir, "en")
+	assert.ErrorContains(t, err, "locale fr")
+
+	_, err = greeter.NewGreetings("", "not a locale")
+	assert.Error(t, err)
+}
+
+func TestReloadGreetings(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en.yaml"), []byte(`hello: "Hi there, {{.Name}}"`), 0o600))
+
+	greetings, err := greeter.NewGreetings("", "en")
+	require.NoError(t, err)
+
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger, greeter.WithGreetings(greetings))
+
+	sayHello := func() string {
+		res, err := s.SayHello(context.Background(), &pb.HelloRequest{Name: "World"})
+		require.NoError(t, err)
+
+		return res.GetMessage()
+	}
+
+	assert.Equal(t, "Hello, World", sayHello())
+
+	require.NoError(t, greeter.ReloadGreetings(greetings, dir, "en"))
+	assert.Equal(t, "Hi there, World", sayHello())
+
+	// Invalid settings keep the current greetings
+	assert.Error(t, greeter.ReloadGreetings(greetings, "", "not a locale"))
+	assert.Error(t, greeter.ReloadGreetings(greetings, filepath.Join(dir, "missing"), "en"))
+	assert.Equal(t, "Hi there, World", sayHello())
 }
 
 func TestGreeterService_Chat(t *testing.T) {
@@ -114,10 +504,588 @@ func TestGreeterService_Chat(t *testing.T) {
 	err := s.Chat(stream)
 
 	assert.NoError(t, err)
-	assert.Len(t, stream.sentReplies, len(requests))
+	require.Len(t, stream.sentReplies, len(requests)+1)
+
+	join := stream.sentReplies[0]
+	assert.Equal(t, pb.ChatEvent_CHAT_EVENT_JOIN, join.Event)
+	assert.Equal(t, greeter.DefaultChatRoom, join.Room)
+	assert.Equal(t, "Dave", join.Sender)
 
-	for i, reply := range stream.sentReplies {
+	for i, reply := range stream.sentReplies[1:] {
 		expected := "Hello, " + requests[i].GetName()
 		assert.Equal(t, expected, reply.Message)
+		assert.Equal(t, pb.ChatEvent_CHAT_EVENT_MESSAGE, reply.Event)
+		assert.Equal(t, "Dave", reply.Sender)
+	}
+}
+
+func TestGreeterService_Chat_RoomFromMetadata(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-chat-room", "general"))
+	stream := &mockGreeterServerStream{ctx: ctx, requests: []*pb.HelloRequest{{Name: "Dave", Room: "ignored"}}}
+
+	require.NoError(t, s.Chat(stream))
+	require.NotEmpty(t, stream.sentReplies)
+	assert.Equal(t, "general", stream.sentReplies[0].Room)
+}
+
+func TestGreeterService_Chat_InvalidRoomMetadata(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	for _, room := range []string{"general chat", "../lobby", strings.Repeat("a", 65)} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-chat-room", room))
+		stream := &mockGreeterServerStream{ctx: ctx, requests: []*pb.HelloRequest{{Name: "Dave"}}}
+
+		err := s.Chat(stream)
+		assert.ErrorIs(t, err, greeter.ErrInvalidChatRoom, room)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Empty(t, stream.sentReplies)
+	}
+}
+
+// chatStream is a Chat stream fed and read through channels. Like those of gRPC, its receives ignore the context.
+type chatStream struct {
+	mockGreeterServerStream
+	recv    chan *pb.HelloRequest
+	sent    chan *pb.HelloReply
+	sendErr error
+}
+
+func (c *chatStream) Recv() (*pb.HelloRequest, error) {
+	req, ok := <-c.recv
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return req, nil
+}
+
+func (c *chatStream) Send(res *pb.HelloReply) error {
+	if c.sendErr != nil {
+		return c.sendErr
+	}
+
+	c.sent <- res
+
+	return nil
+}
+
+// nextChatMessage returns the next greeting sent on the stream within the timeout, skipping join and leave events.
+func nextChatMessage(c *chatStream, timeout time.Duration) *pb.HelloReply {
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case reply := <-c.sent:
+			if reply.GetEvent() == pb.ChatEvent_CHAT_EVENT_MESSAGE {
+				return reply
+			}
+		case <-deadline:
+			return nil
+		}
+	}
+}
+
+func TestGreeterService_Chat_NoPublishAfterLeave(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	alice := &chatStream{
+		mockGreeterServerStream: mockGreeterServerStream{ctx: context.Background()},
+		recv:                    make(chan *pb.HelloRequest, 1),
+		sent:                    make(chan *pb.HelloReply, 16),
+	}
+	alice.recv <- &pb.HelloRequest{Name: "Alice", Room: "lobby"}
+
+	aliceDone := make(chan error, 1)
+
+	go func() {
+		aliceDone <- s.Chat(alice)
+	}()
+
+	assert.Equal(t, "Hello, Alice", nextChatMessage(alice, time.Second).GetMessage())
+
+	// Bob fails to receive his own greeting and leaves while his next receive is pending
+	bob := &chatStream{
+		mockGreeterServerStream: mockGreeterServerStream{ctx: context.Background()},
+		recv:                    make(chan *pb.HelloRequest, 1),
+		sendErr:                 status.Error(codes.Unavailable, "connection lost"),
 	}
+	bob.recv <- &pb.HelloRequest{Name: "Bob", Room: "lobby"}
+
+	err := s.Chat(bob)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "Hello, Bob", nextChatMessage(alice, time.Second).GetMessage())
+
+	bob.recv <- &pb.HelloRequest{Name: "Bob again", Room: "lobby"}
+
+	assert.Nil(t, nextChatMessage(alice, 50*time.Millisecond))
+
+	close(alice.recv)
+	require.NoError(t, <-aliceDone)
+}
+
+func TestGreeterService_Chat_Close(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := &mockGreeterServerStream{ctx: ctx, requests: []*pb.HelloRequest{{Name: "Dave"}}, blockWhenDrained: true}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- s.Chat(stream)
+	}()
+
+	// Wait for the member to join before shutting down
+	time.Sleep(20 * time.Millisecond)
+	s.Close()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, greeter.ErrChatClosed)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	case <-time.After(time.Second):
+		t.Fatal("Chat did not return after Close")
+	}
+}
+
+func TestGreeterService_StreamLimits(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+
+	names := func(n int, name string) []*pb.HelloRequest {
+		requests := make([]*pb.HelloRequest, n)
+		for i := range requests {
+			requests[i] = &pb.HelloRequest{Name: name}
+		}
+
+		return requests
+	}
+
+	tests := []struct {
+		name     string
+		limits   greeter.StreamLimits
+		requests []*pb.HelloRequest
+		expected error
+		code     codes.Code
+	}{
+		{
+			name:     "within limits",
+			limits:   greeter.StreamLimits{MaxMessages: 3, MaxBytes: 100},
+			requests: names(3, "Alice"),
+		},
+		{
+			name:     "too many messages",
+			limits:   greeter.StreamLimits{MaxMessages: 2},
+			requests: names(3, "Alice"),
+			expected: greeter.ErrTooManyMessages,
+			code:     codes.ResourceExhausted,
+		},
+		{
+			name:     "too many bytes",
+			limits:   greeter.StreamLimits{MaxBytes: 20},
+			requests: names(3, "Alice"),
+			expected: greeter.ErrStreamTooLarge,
+			code:     codes.ResourceExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, method := range []string{"GreetManyTimes", "Chat"} {
+			t.Run(tt.name+"/"+method, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				s := greeter.NewService(logger, greeter.WithStreamLimits(tt.limits))
+				stream := &mockGreeterServerStream{ctx: ctx, requests: tt.requests}
+
+				var err error
+				if method == "Chat" {
+					err = s.Chat(stream)
+				} else {
+					err = s.GreetManyTimes(stream)
+				}
+
+				if tt.expected == nil {
+					assert.NoError(t, err)
+					return
+				}
+
+				assert.ErrorIs(t, err, tt.expected)
+				assert.Equal(t, tt.code, status.Code(err))
+			})
+		}
+	}
+}
+
+// serveGreeter serves the service on a bufconn listener behind the stream deadline interceptor, and returns a
+// client of it.
+func serveGreeter(t *testing.T, s *greeter.Service, opts ...middleware.DeadlineOption) pb.GreeterClient {
+	t.Helper()
+
+	log, _ := logger.NewZapLogger("test", false)
+	listener := bufconn.Listen(1024 * 1024)
+
+	gs := grpc.NewServer(grpc.ChainStreamInterceptor(
+		middleware.StreamDeadlineInterceptor(opts...),
+		grpcerr.StreamServerInterceptor(log),
+	))
+	pb.RegisterGreeterServer(gs, s)
+
+	go func() {
+		_ = gs.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		gs.Stop()
+	})
+
+	return pb.NewGreeterClient(conn)
+}
+
+// reason returns the ErrorInfo reason of a status error.
+func reason(err error) string {
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+
+	return ""
+}
+
+// sendEvery sends a greeting on the stream at the interval until the stream ends.
+func sendEvery(stream grpc.ClientStream, interval time.Duration, name string) {
+	for stream.SendMsg(&pb.HelloRequest{Name: name, Room: "deadlines"}) == nil {
+		time.Sleep(interval)
+	}
+}
+
+func TestGreeterService_StreamDeadlines(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+
+	tests := []struct {
+		name     string
+		policy   middleware.DeadlinePolicy
+		interval time.Duration
+		reason   string
+	}{
+		{
+			name:   "idle timeout",
+			policy: middleware.DeadlinePolicy{StreamIdle: 50 * time.Millisecond},
+			reason: "STREAM_IDLE_TIMEOUT",
+		},
+		{
+			name:     "max duration",
+			policy:   middleware.DeadlinePolicy{StreamIdle: time.Second, StreamMax: 150 * time.Millisecond},
+			interval: 20 * time.Millisecond,
+			reason:   "STREAM_MAX_DURATION",
+		},
+	}
+
+	for _, tt := range tests {
+		for _, method := range []string{"GreetManyTimes", "Chat"} {
+			t.Run(tt.name+"/"+method, func(t *testing.T) {
+				client := serveGreeter(t, greeter.NewService(logger), middleware.WithDefaultDeadlinePolicy(tt.policy))
+
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				var (
+					stream grpc.ClientStream
+					err    error
+				)
+
+				if method == "Chat" {
+					stream, err = client.Chat(ctx)
+				} else {
+					stream, err = client.GreetManyTimes(ctx)
+				}
+
+				require.NoError(t, err)
+				require.NoError(t, stream.SendMsg(&pb.HelloRequest{Name: "Alice", Room: "deadlines"}))
+
+				if tt.interval > 0 {
+					go sendEvery(stream, tt.interval, "Alice")
+				}
+
+				for err == nil {
+					err = stream.RecvMsg(&pb.HelloReply{})
+				}
+
+				assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+				assert.Equal(t, tt.reason, reason(err))
+			})
+		}
+	}
+}
+
+func TestGreeterService_StreamDeadlines_ChatListener(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	client := serveGreeter(t, greeter.NewService(logger),
+		middleware.WithDefaultDeadlinePolicy(middleware.DeadlinePolicy{StreamIdle: 100 * time.Millisecond}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	listener, err := client.Chat(ctx)
+	require.NoError(t, err)
+	require.NoError(t, listener.Send(&pb.HelloRequest{Name: "Dave", Room: "deadlines"}))
+
+	// Wait for the listener to join, then keep the room busy
+	_, err = listener.Recv()
+	require.NoError(t, err)
+
+	talker, err := client.Chat(ctx)
+	require.NoError(t, err)
+
+	go sendEvery(talker, 20*time.Millisecond, "Eve")
+
+	// Broadcasts do not keep a member that sends nothing from being idle
+	broadcasts := 0
+
+	for {
+		reply, err := listener.Recv()
+		if err != nil {
+			assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+			assert.Equal(t, "STREAM_IDLE_TIMEOUT", reason(err))
+
+			break
+		}
+
+		if reply.GetSender() == "Eve" {
+			broadcasts++
+		}
+	}
+
+	assert.Positive(t, broadcasts)
+}
+
+func TestGreeterService_SetLimits(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	many := func() error {
+		stream := &mockGreeterServerStream{ctx: context.Background(), requests: []*pb.HelloRequest{
+			{Name: "Alice"}, {Name: "Bob"}, {Name: "Carol"},
+		}}
+
+		return s.GreetManyTimes(stream)
+	}
+
+	streamed := func() int {
+		stream := &mockGreeterServerStream{ctx: context.Background()}
+		require.NoError(t, s.StreamGreetings(&pb.HelloRequest{Name: "Streamer", Count: 10}, stream))
+
+		return len(stream.sentReplies)
+	}
+
+	require.NoError(t, many())
+	assert.Equal(t, 10, streamed())
+
+	s.SetStreamLimits(greeter.StreamLimits{MaxMessages: 2})
+	s.SetStreamGreetingsLimits(greeter.StreamGreetingsLimits{DefaultCount: 1, MaxCount: 4})
+
+	assert.ErrorIs(t, many(), greeter.ErrTooManyMessages)
+	assert.Equal(t, 4, streamed())
+}
+
+func TestGreeterService_History(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger, greeter.WithStore(store.NewMemoryStore(0)))
+	ctx := context.Background()
+
+	for _, name := range []string{"Alice", "Bob", "Alice"} {
+		_, err := s.SayHello(ctx, &pb.HelloRequest{Name: name})
+		require.NoError(t, err)
+	}
+
+	many := &mockGreeterServerStream{ctx: ctx, requests: []*pb.HelloRequest{{Name: "Carol"}, {Name: "Carol"}}}
+	require.NoError(t, s.GreetManyTimes(many))
+
+	chat := &mockGreeterServerStream{ctx: ctx, requests: []*pb.HelloRequest{{Name: "Dave", Room: "general"}}}
+	require.NoError(t, s.Chat(chat))
+
+	res, err := s.ListGreetings(ctx, &pb.ListGreetingsRequest{})
+	require.NoError(t, err)
+	require.Len(t, res.GetGreetings(), 5)
+	assert.Empty(t, res.GetNextPageToken())
+
+	last := res.GetGreetings()[4]
+	assert.Equal(t, "Chat", last.GetMethod())
+	assert.Equal(t, "general", last.GetRoom())
+	assert.Equal(t, "Dave", last.GetSender())
+
+	// Filter by name, one greeting per page
+	var names []string
+
+	req := &pb.ListGreetingsRequest{Name: "Alice", PageSize: 1}
+	for {
+		res, err := s.ListGreetings(ctx, req)
+		require.NoError(t, err)
+
+		for _, g := range res.GetGreetings() {
+			names = append(names, g.GetName())
+		}
+
+		if res.GetNextPageToken() == "" {
+			break
+		}
+
+		req.PageToken = res.GetNextPageToken()
+	}
+
+	assert.Equal(t, []string{"Alice", "Alice"}, names)
+
+	// Filter by time
+	res, err = s.ListGreetings(ctx, &pb.ListGreetingsRequest{Since: timestamppb.New(time.Now().Add(time.Hour))})
+	require.NoError(t, err)
+	assert.Empty(t, res.GetGreetings())
+
+	// Filter expression, order and read mask
+	res, err = s.ListGreetings(ctx, &pb.ListGreetingsRequest{
+		Filter:   `method = "SayHello" OR method = "GreetManyTimes"`,
+		OrderBy:  "create_time desc",
+		ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.GetGreetings(), 4)
+	assert.Equal(t, "Carol", res.GetGreetings()[0].GetName())
+	assert.Equal(t, "Alice", res.GetGreetings()[3].GetName())
+	assert.Empty(t, res.GetGreetings()[0].GetId())
+	assert.Empty(t, res.GetGreetings()[0].GetMessage())
+
+	_, err = s.ListGreetings(ctx, &pb.ListGreetingsRequest{OrderBy: "message"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	got, err :=s.GetGreeting(ctx, &pb.GetGreetingRequest{Id: last.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, last.GetMessage(), got.GetMessage())
+
+	_, err = s.GetGreeting(ctx, &pb.GetGreetingRequest{Id: "01ARZ3NDEKTSV4RRFFQ69G5FAV"})
+	assert.ErrorIs(t, err, greeter.ErrGreetingNotFound)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.ListGreetings(ctx, &pb.ListGreetingsRequest{PageToken: "!"})
+	assert.ErrorIs(t, err, pagination.ErrInvalidPageToken)
+}
+
+// queryRecordingStore is a store.Store recording the queries of List.
+type queryRecordingStore struct {
+	store.Store
+	queries []store.Query
+	read    int
+}
+
+func (r *queryRecordingStore) List(ctx context.Context, q store.Query) (*store.Page, error) {
+	page, err := r.Store.List(ctx, q)
+	if err == nil {
+		r.queries = append(r.queries, q)
+		r.read += len(page.Greetings)
+	}
+
+	return page, err
+}
+
+func TestGreeterService_ListGreetings_Paging(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	st := &queryRecordingStore{Store: store.NewMemoryStore(0)}
+	s := greeter.NewService(logger, greeter.WithStore(st), greeter.WithMaxOrderedGreetings(5))
+	ctx := context.Background()
+
+	for i := range 10 {
+		_, err := s.SayHello(ctx, &pb.HelloRequest{Name: []string{"Alice", "Bob"}[i%2]})
+		require.NoError(t, err)
+	}
+
+	// The default order reads a page at a time from the cursor
+	var pages [][]string
+
+	req := &pb.ListGreetingsRequest{Filter: `name = "Alice"`, PageSize: 2}
+	for {
+		st.queries, st.read = nil, 0
+
+		res, err := s.ListGreetings(ctx, req)
+		require.NoError(t, err)
+
+		var page []string
+		for _, g := range res.GetGreetings() {
+			page = append(page, g.GetName())
+		}
+
+		pages = append(pages, page)
+
+		for _, q := range st.queries {
+			assert.Equal(t, 3, q.Limit)
+		}
+
+		assert.LessOrEqual(t, st.read, 6)
+
+		if res.GetNextPageToken() == "" {
+			break
+		}
+
+		assert.NotEmpty(t, st.queries[len(st.queries)-1].After)
+		req.PageToken = res.GetNextPageToken()
+	}
+
+	assert.Equal(t, [][]string{{"Alice", "Alice"}, {"Alice", "Alice"}, {"Alice"}}, pages)
+
+	// A custom order reads every greeting selected, up to the maximum
+	st.queries = nil
+
+	res, err := s.ListGreetings(ctx, &pb.ListGreetingsRequest{Name: "Bob", OrderBy: "name desc", PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, st.queries, 1)
+	assert.Equal(t, 6, st.queries[0].Limit)
+	assert.Equal(t, "Bob", res.GetGreetings()[0].GetName())
+
+	_, err = s.ListGreetings(ctx, &pb.ListGreetingsRequest{OrderBy: "name desc", PageSize: 2})
+	assert.ErrorIs(t, err, greeter.ErrTooManyToOrder)
+
+	// Page tokens are bound to the other fields of the request
+	res, err = s.ListGreetings(ctx, &pb.ListGreetingsRequest{Name: "Alice", PageSize: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetNextPageToken())
+
+	for _, other := range []*pb.ListGreetingsRequest{
+		{Name: "Bob", PageSize: 1, PageToken: res.GetNextPageToken()},
+		{Name: "Alice", Since: timestamppb.New(time.Now().Add(-time.Hour)), PageToken: res.GetNextPageToken()},
+	} {
+		_, err := s.ListGreetings(ctx, other)
+		assert.ErrorIs(t, err, pagination.ErrInvalidPageToken)
+	}
+
+	// The page size and read mask may change between pages
+	_, err = s.ListGreetings(ctx, &pb.ListGreetingsRequest{
+		Name: "Alice", PageSize: 3, PageToken: res.GetNextPageToken(),
+		ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestGreeterService_HistoryDisabled(t *testing.T) {
+	logger, _ := logger.NewZapLogger("test", false)
+	s := greeter.NewService(logger)
+
+	_, err := s.ListGreetings(context.Background(), &pb.ListGreetingsRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	_, err = s.GetGreeting(context.Background(), &pb.GetGreetingRequest{Id: "01ARZ3NDEKTSV4RRFFQ69G5FAV"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
 }
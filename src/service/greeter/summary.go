@@ -0,0 +1,67 @@
+package greeter
+
+import (
+	"context"
+
+	pb "github.com/mrityunjoydey/go-grpc/rpc"
+)
+
+// nameTally collects the names received on a stream, keeping the order in which distinct names first appear.
+type nameTally struct {
+	names  []string
+	times  map[string]int
+	total  int
+	locale string
+}
+
+func newNameTally() *nameTally {
+	return &nameTally{times: make(map[string]int)}
+}
+
+// add records a received request. The first locale sent on the stream selects the locale of the summaries.
+func (t *nameTally) add(req *pb.HelloRequest) {
+	name := req.GetName()
+	if t.times[name] == 0 {
+		t.names = append(t.names, name)
+	}
+
+	t.times[name]++
+	t.total++
+
+	if t.locale == "" {
+		t.locale = req.GetLocale()
+	}
+}
+
+// summarize renders the summary of the names received so far.
+func (s *Service) summarize(ctx context.Context, t *nameTally, final bool) (*pb.GreetingSummary, error) {
+	message, locale, err := s.greet(ctx, t.locale, greetingMany, greeting{Names: t.names, Count: len(t.names)})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &pb.GreetingSummary{
+		Names:       append([]string(nil), t.names...),
+		Count:       uint32(t.total),
+		UniqueCount: uint32(len(t.names)),
+		Greetings:   make([]*pb.NameGreeting, 0, len(t.names)),
+		Final:       final,
+		Message:     message,
+		Locale:      locale,
+	}
+
+	for _, name := range t.names {
+		greeted, _, err := s.greet(ctx, t.locale, greetingHello, greeting{Name: name, Count: 1})
+		if err != nil {
+			return nil, err
+		}
+
+		summary.Greetings = append(summary.Greetings, &pb.NameGreeting{
+			Name:    name,
+			Message: greeted,
+			Times:   uint32(t.times[name]),
+		})
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// greetingsBucket holds the greetings keyed by ID, so that cursors iterate them by creation time.
+var greetingsBucket = []byte("greetings")
+
+// BoltStore keeps greetings in an embedded BoltDB file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens the BoltDB file at path, creating it and its directory when missing.
+// The file is locked while open, so a second process opening it waits up to a second and fails.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create greeting store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open greeting store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(greetingsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize greeting store %s: %w", path, err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Add implements Store.
+func (s *BoltStore) Add(_ context.Context, g *Greeting) error {
+	prepare(g)
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(greetingsBucket).Put([]byte(g.ID), data)
+	})
+}
+
+// Get implements Store.
+func (s *BoltStore) Get(_ context.Context, id string) (*Greeting, error) {
+	var g *Greeting
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(greetingsBucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+
+		g = &Greeting{}
+
+		return json.Unmarshal(data, g)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// List implements Store.
+func (s *BoltStore) List(ctx context.Context, q Query) (*Page, error) {
+	c := &collector{q: q}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(greetingsBucket).Cursor()
+
+		k, v := cursor.First()
+		if q.After != "" {
+			k, v = cursor.Seek([]byte(q.After))
+			if k != nil && string(k) == q.After {
+				k, v = cursor.Next()
+			}
+		}
+
+		for ; k != nil; k, v = cursor.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			g := &Greeting{}
+			if err := json.Unmarshal(v, g); err != nil {
+				return fmt.Errorf("corrupt greeting %s: %w", k, err)
+			}
+
+			if !c.add(g) {
+				return nil
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &c.page, nil
+}
+
+// Close implements Store.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
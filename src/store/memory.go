@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps greetings in memory. It loses them on restart, and keeps at most its capacity by dropping
+// the oldest greetings.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	greetings []*Greeting // sorted by ID
+	capacity  int
+}
+
+// NewMemoryStore creates a MemoryStore holding at most capacity greetings. Zero keeps every greeting.
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{capacity: capacity}
+}
+
+// Add implements Store.
+func (s *MemoryStore) Add(_ context.Context, g *Greeting) error {
+	prepare(g)
+	stored := *g
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.search(stored.ID)
+	s.greetings = append(s.greetings, nil)
+	copy(s.greetings[i+1:], s.greetings[i:])
+	s.greetings[i] = &stored
+
+	if s.capacity > 0 && len(s.greetings) > s.capacity {
+		s.greetings = s.greetings[len(s.greetings)-s.capacity:]
+	}
+
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, id string) (*Greeting, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.search(id)
+	if i == len(s.greetings) || s.greetings[i].ID != id {
+		return nil, ErrNotFound
+	}
+
+	g := *s.greetings[i]
+
+	return &g, nil
+}
+
+// List implements Store.
+func (s *MemoryStore) List(_ context.Context, q Query) (*Page, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c := &collector{q: q}
+
+	start := 0
+	if q.After != "" {
+		start = sort.Search(len(s.greetings), func(i int) bool { return s.greetings[i].ID > q.After })
+	}
+
+	for _, g := range s.greetings[start:] {
+		copied := *g
+		if !c.add(&copied) {
+			break
+		}
+	}
+
+	return &c.page, nil
+}
+
+// Close implements Store.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// search returns the index of the first greeting whose ID is not before id.
+func (s *MemoryStore) search(id string) int {
+	return sort.Search(len(s.greetings), func(i int) bool { return s.greetings[i].ID >= id })
+}
@@ -0,0 +1,112 @@
+// Package store persists the history of greetings sent by the Greeter service.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// ErrNotFound is returned when a greeting does not exist.
+var ErrNotFound = errors.New("greeting not found")
+
+// Greeting is a greeting sent by the service.
+type Greeting struct {
+	// ID identifies the greeting. IDs are ULIDs, so they sort by creation time.
+	ID string `json:"id"`
+	// Name is the name greeted.
+	Name string `json:"name"`
+	// Message is the greeting sent.
+	Message string `json:"message"`
+	// Method is the RPC method that sent the greeting, e.g. "SayHello".
+	Method string `json:"method"`
+	// Locale is the locale the greeting was rendered in.
+	Locale string `json:"locale,omitempty"`
+	// Room is the Chat room the greeting was sent in.
+	Room string `json:"room,omitempty"`
+	// Sender is the Chat room member who sent the greeting.
+	Sender string `json:"sender,omitempty"`
+	// CreatedAt is the time the greeting was sent.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Query selects a page of greetings, ordered by creation time.
+type Query struct {
+	// Name keeps only the greetings of this name when set.
+	Name string
+	// Since keeps only the greetings sent at or after this time when set.
+	Since time.Time
+	// Until keeps only the greetings sent before this time when set.
+	Until time.Time
+	// After starts the page after the greeting with this ID, as returned in Page.Next.
+	After string
+	// Limit is the maximum number of greetings returned. Zero returns every greeting.
+	Limit int
+}
+
+// matches reports whether the greeting passes the filters of the query.
+func (q Query) matches(g *Greeting) bool {
+	if q.Name != "" && g.Name != q.Name {
+		return false
+	}
+
+	return q.Since.IsZero() || !g.CreatedAt.Before(q.Since)
+}
+
+// Page is a page of greetings.
+type Page struct {
+	Greetings []*Greeting
+	// Next is the Query.After of the next page, or empty on the last page.
+	Next string
+}
+
+// Store persists greetings. Implementations must be safe for concurrent use.
+type Store interface {
+	// Add stores the greeting, setting its ID and, when zero, its CreatedAt.
+	Add(ctx context.Context, g *Greeting) error
+	// Get returns the greeting with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Greeting, error)
+	// List returns the page of greetings selected by the query.
+	List(ctx context.Context, q Query) (*Page, error)
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// prepare sets the ID and creation time of a new greeting.
+func prepare(g *Greeting) {
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = time.Now()
+	}
+
+	g.CreatedAt = g.CreatedAt.UTC()
+	g.ID = ulid.MustNew(ulid.Timestamp(g.CreatedAt), ulid.DefaultEntropy()).String()
+}
+
+// collector accumulates the greetings of a page while a store scans them in ID order.
+type collector struct {
+	q    Query
+	page Page
+}
+
+// add offers the next greeting in ID order. It returns false once the page is full or the greetings are past
+// the end of the queried time range.
+func (c *collector) add(g *Greeting) bool {
+	if !c.q.Until.IsZero() && !g.CreatedAt.Before(c.q.Until) {
+		return false
+	}
+
+	if !c.q.matches(g) {
+		return true
+	}
+
+	if c.q.Limit > 0 && len(c.page.Greetings) == c.q.Limit {
+		c.page.Next = c.page.Greetings[len(c.page.Greetings)-1].ID
+		return false
+	}
+
+	c.page.Greetings = append(c.page.Greetings, g)
+
+	return true
+}
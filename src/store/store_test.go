@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stores returns a fresh instance of every Store implementation.
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	bolt, err := OpenBoltStore(filepath.Join(t.TempDir(), "greetings.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { assert.NoError(t, bolt.Close()) })
+
+	return map[string]Store{
+		"memory": NewMemoryStore(0),
+		"bolt":   bolt,
+	}
+}
+
+func TestStore_AddGet(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			g := &Greeting{Name: "Alice", Message: "Hello, Alice", Method: "SayHello", Locale: "en"}
+
+			require.NoError(t, s.Add(ctx, g))
+			assert.NotEmpty(t, g.ID)
+			assert.False(t, g.CreatedAt.IsZero())
+
+			got, err := s.Get(ctx, g.ID)
+			require.NoError(t, err)
+			assert.Equal(t, g.Message, got.Message)
+			assert.True(t, g.CreatedAt.Equal(got.CreatedAt))
+
+			_, err = s.Get(ctx, "unknown")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+func TestStore_List(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// Added out of order: listing follows creation time
+			for i, n := range []int{3, 0, 4, 1, 2} {
+				name := "Alice"
+				if i%2 == 1 {
+					name = "Bob"
+				}
+
+				require.NoError(t, s.Add(ctx, &Greeting{Name: name, Message: "Hello", CreatedAt: base.Add(time.Duration(n) * time.Minute)}))
+			}
+
+			minutes := func(p *Page) []int {
+				var out []int
+				for _, g := range p.Greetings {
+					out = append(out, int(g.CreatedAt.Sub(base)/time.Minute))
+				}
+
+				return out
+			}
+
+			tests := []struct {
+				name     string
+				q        Query
+				expected []int
+			}{
+				{name: "all", q: Query{}, expected: []int{0, 1, 2, 3, 4}},
+				{name: "by name", q: Query{Name: "Bob"}, expected: []int{0, 1}},
+				{name: "since", q: Query{Since: base.Add(2 * time.Minute)}, expected: []int{2, 3, 4}},
+				{name: "until", q: Query{Until: base.Add(2 * time.Minute)}, expected: []int{0, 1}},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					page, err := s.List(ctx, tt.q)
+					require.NoError(t, err)
+					assert.Equal(t, tt.expected, minutes(page))
+					assert.Empty(t, page.Next)
+				})
+			}
+
+			// Pages follow each other without gaps or duplicates
+			var all []int
+
+			q := Query{Limit: 2}
+			for {
+				page, err := s.List(ctx, q)
+				require.NoError(t, err)
+
+				all = append(all, minutes(page)...)
+				if page.Next == "" {
+					break
+				}
+
+				q.After = page.Next
+			}
+
+			assert.Equal(t, []int{0, 1, 2, 3, 4}, all)
+		})
+	}
+}
+
+func TestMemoryStore_Capacity(t *testing.T) {
+	s := NewMemoryStore(2)
+	ctx := context.Background()
+
+	for _, name := range []string{"Alice", "Bob", "Charlie"} {
+		require.NoError(t, s.Add(ctx, &Greeting{Name: name}))
+	}
+
+	page, err := s.List(ctx, Query{})
+	require.NoError(t, err)
+	require.Len(t, page.Greetings, 2)
+	assert.Equal(t, "Bob", page.Greetings[0].Name)
+	assert.Equal(t, "Charlie", page.Greetings[1].Name)
+}
+
+func TestBoltStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "greetings.db")
+	ctx := context.Background()
+
+	s, err := OpenBoltStore(path)
+	require.NoError(t, err)
+
+	g := &Greeting{Name: "Alice"}
+	require.NoError(t, s.Add(ctx, g))
+	require.NoError(t, s.Close())
+
+	s, err = OpenBoltStore(path)
+	require.NoError(t, err)
+
+	defer s.Close()
+
+	got, err := s.Get(ctx, g.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", got.Name)
+}